@@ -1,7 +1,7 @@
 package main
 
 import (
-	"net"
+	"context"
 	"net/http"
 	"os"
 
@@ -11,25 +11,13 @@ import (
 	"github.com/Financial-Times/public-organisations-api/v3/organisations"
 	status "github.com/Financial-Times/service-status-go/httphandlers"
 
-	"fmt"
-	"strconv"
-	"time"
-
 	"github.com/gorilla/mux"
 	cli "github.com/jawher/mow.cli"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	metrics "github.com/rcrowley/go-metrics"
 )
 
-var httpClient = http.Client{
-	Transport: &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout: 15 * time.Second,
-		}).DialContext,
-		MaxIdleConnsPerHost: 128,
-		IdleConnTimeout:     60 * time.Second,
-	},
-}
-
 func main() {
 	app := cli.App("public-organisations-api", "A public RESTful API for accessing organisations in Neo4j")
 	appSystemCode := app.String(cli.StringOpt{
@@ -62,46 +50,603 @@ func main() {
 		Desc:   "Public concepts API endpoint URL.",
 		EnvVar: "CONCEPTS_API",
 	})
+	organisationSource := app.String(cli.StringOpt{
+		Name:   "organisation-source",
+		Value:  "concepts-api",
+		Desc:   "Where organisations are read from: concepts-api, or memory to serve the concepts in organisation-source-file",
+		EnvVar: "ORGANISATION_SOURCE",
+	})
+	organisationSourceFile := app.String(cli.StringOpt{
+		Name:   "organisation-source-file",
+		Value:  "",
+		Desc:   "JSON file of the concepts served by the memory organisation source",
+		EnvVar: "ORGANISATION_SOURCE_FILE",
+	})
+	contractValidation := app.String(cli.StringOpt{
+		Name:   "contract-validation",
+		Value:  "off",
+		Desc:   "Validate every organisation against the API definition before it is returned: off, report to log and count violations, or enforce to also fail the request",
+		EnvVar: "CONTRACT_VALIDATION",
+	})
+	recordDir := app.String(cli.StringOpt{
+		Name:   "record-dir",
+		Value:  "",
+		Desc:   "Directory every public-concepts-api response is saved in, to be replayed with replay-dir",
+		EnvVar: "RECORD_DIR",
+	})
+	replayDir := app.String(cli.StringOpt{
+		Name:   "replay-dir",
+		Value:  "",
+		Desc:   "Directory of recorded public-concepts-api responses to serve instead of calling public-concepts-api",
+		EnvVar: "REPLAY_DIR",
+	})
+	allowedTypes := app.Strings(cli.StringsOpt{
+		Name:   "allowed-types",
+		Value:  []string{},
+		Desc:   "Organisation types to serve, as URIs or relative to the FT ontology e.g. company/PublicCompany. Empty serves every subtype of Organisation.",
+		EnvVar: "ALLOWED_TYPES",
+	})
+	deniedTypes := app.Strings(cli.StringsOpt{
+		Name:   "denied-types",
+		Value:  []string{},
+		Desc:   "Organisation types never to serve, as URIs or relative to the FT ontology. Subtypes of a denied type are denied too.",
+		EnvVar: "DENIED_TYPES",
+	})
+	tracingExporter := app.String(cli.StringOpt{
+		Name:   "tracing-exporter",
+		Value:  "off",
+		Desc:   "Where to send OpenTelemetry traces: otlp, stdout or off. The otlp exporter is configured with the standard OTEL_EXPORTER_OTLP_* environment variables.",
+		EnvVar: "TRACING_EXPORTER",
+	})
+	historyDB := app.String(cli.StringOpt{
+		Name:   "history-db",
+		Value:  "",
+		Desc:   "Path of the on-disk store recording every distinct version of the organisations served. Empty disables history.",
+		EnvVar: "HISTORY_DB",
+	})
+	readTimeout := app.String(cli.StringOpt{
+		Name:   "read-timeout",
+		Value:  "10s",
+		Desc:   "Maximum duration for reading an entire request, including the body",
+		EnvVar: "READ_TIMEOUT",
+	})
+	readHeaderTimeout := app.String(cli.StringOpt{
+		Name:   "read-header-timeout",
+		Value:  "5s",
+		Desc:   "Maximum duration for reading request headers",
+		EnvVar: "READ_HEADER_TIMEOUT",
+	})
+	writeTimeout := app.String(cli.StringOpt{
+		Name:   "write-timeout",
+		Value:  "30s",
+		Desc:   "Maximum duration before timing out writes of the response",
+		EnvVar: "WRITE_TIMEOUT",
+	})
+	idleTimeout := app.String(cli.StringOpt{
+		Name:   "idle-timeout",
+		Value:  "120s",
+		Desc:   "Maximum duration to wait for the next request on a keep-alive connection",
+		EnvVar: "IDLE_TIMEOUT",
+	})
+	preStopDelay := app.String(cli.StringOpt{
+		Name:   "pre-stop-delay",
+		Value:  "5s",
+		Desc:   "How long /__gtg fails after SIGTERM before the server stops accepting connections",
+		EnvVar: "PRE_STOP_DELAY",
+	})
+	shutdownTimeout := app.String(cli.StringOpt{
+		Name:   "shutdown-timeout",
+		Value:  "20s",
+		Desc:   "Maximum duration to wait for in-flight requests to complete on shutdown",
+		EnvVar: "SHUTDOWN_TIMEOUT",
+	})
+	healthInterval := app.String(cli.StringOpt{
+		Name:   "health-interval",
+		Value:  "10s",
+		Desc:   "How often the health checks run in the background to answer /__health and /__gtg",
+		EnvVar: "HEALTH_INTERVAL",
+	})
+	healthTimeout := app.String(cli.StringOpt{
+		Name:   "health-timeout",
+		Value:  "10s",
+		Desc:   "Maximum duration of a health check before it fails",
+		EnvVar: "HEALTH_TIMEOUT",
+	})
+	healthDegradedLatency := app.String(cli.StringOpt{
+		Name:   "health-degraded-latency",
+		Value:  "2s",
+		Desc:   "Duration over which a passing health check is reported as degraded. 0s disables the degraded state.",
+		EnvVar: "HEALTH_DEGRADED_LATENCY",
+	})
+	configFile := app.String(cli.StringOpt{
+		Name:   "config-file",
+		Value:  "",
+		Desc:   "YAML or JSON file setting any of the other options by name, overriding the command line. Reloadable options are applied when the file changes or on SIGHUP.",
+		EnvVar: "CONFIG_FILE",
+	})
+	canaryUUID := app.String(cli.StringOpt{
+		Name:   "canary-uuid",
+		Value:  "",
+		Desc:   "UUID of a known organisation the canary health check reads and transforms end to end. Empty disables the check.",
+		EnvVar: "CANARY_UUID",
+	})
+	clientTimeout := app.String(cli.StringOpt{
+		Name:   "client-timeout",
+		Value:  "10s",
+		Desc:   "Maximum duration of a request to public-concepts-api, including reading the body. 0s means no timeout.",
+		EnvVar: "CLIENT_TIMEOUT",
+	})
+	clientDialTimeout := app.String(cli.StringOpt{
+		Name:   "client-dial-timeout",
+		Value:  "15s",
+		Desc:   "Maximum duration to establish a connection to public-concepts-api",
+		EnvVar: "CLIENT_DIAL_TIMEOUT",
+	})
+	clientTLSHandshakeTimeout := app.String(cli.StringOpt{
+		Name:   "client-tls-handshake-timeout",
+		Value:  "10s",
+		Desc:   "Maximum duration of the TLS handshake with public-concepts-api",
+		EnvVar: "CLIENT_TLS_HANDSHAKE_TIMEOUT",
+	})
+	clientResponseHeaderTimeout := app.String(cli.StringOpt{
+		Name:   "client-response-header-timeout",
+		Value:  "0s",
+		Desc:   "Maximum duration to wait for the response headers of public-concepts-api once the request is sent. 0s means no timeout.",
+		EnvVar: "CLIENT_RESPONSE_HEADER_TIMEOUT",
+	})
+	clientIdleConnTimeout := app.String(cli.StringOpt{
+		Name:   "client-idle-conn-timeout",
+		Value:  "60s",
+		Desc:   "How long an idle connection to public-concepts-api is kept in the pool",
+		EnvVar: "CLIENT_IDLE_CONN_TIMEOUT",
+	})
+	clientKeepAlive := app.String(cli.StringOpt{
+		Name:   "client-keep-alive",
+		Value:  "30s",
+		Desc:   "TCP keep-alive period of connections to public-concepts-api. 0s disables TCP keep-alives.",
+		EnvVar: "CLIENT_KEEP_ALIVE",
+	})
+	clientReuseConnections := app.Bool(cli.BoolOpt{
+		Name:   "client-reuse-connections",
+		Value:  true,
+		Desc:   "Reuse connections to public-concepts-api for later requests rather than closing them after each response",
+		EnvVar: "CLIENT_REUSE_CONNECTIONS",
+	})
+	clientMaxIdleConnsPerHost := app.Int(cli.IntOpt{
+		Name:   "client-max-idle-conns-per-host",
+		Value:  128,
+		Desc:   "Maximum number of idle connections kept in the pool for each host",
+		EnvVar: "CLIENT_MAX_IDLE_CONNS_PER_HOST",
+	})
+	clientMaxConnsPerHost := app.Int(cli.IntOpt{
+		Name:   "client-max-conns-per-host",
+		Value:  0,
+		Desc:   "Maximum number of connections to each host, including those in use. 0 means no limit.",
+		EnvVar: "CLIENT_MAX_CONNS_PER_HOST",
+	})
+	clientHTTP2 := app.Bool(cli.BoolOpt{
+		Name:   "client-http2",
+		Value:  false,
+		Desc:   "Attempt HTTP/2 when connecting to public-concepts-api over TLS",
+		EnvVar: "CLIENT_HTTP2",
+	})
+	clientProxy := app.String(cli.StringOpt{
+		Name:   "client-proxy",
+		Value:  "",
+		Desc:   "URL of the proxy to call public-concepts-api through. Empty connects directly.",
+		EnvVar: "CLIENT_PROXY",
+	})
+	rateLimit := app.String(cli.StringOpt{
+		Name:   "rate-limit",
+		Value:  "",
+		Desc:   "Limit of each consumer without a limit of its own, in requests a second and burst e.g. 10:20. Empty means unlimited.",
+		EnvVar: "RATE_LIMIT",
+	})
+	clientRateLimits := app.Strings(cli.StringsOpt{
+		Name:   "client-rate-limits",
+		Value:  []string{},
+		Desc:   "Limits of particular consumers, as consumer=rate:burst e.g. next-app=50:100",
+		EnvVar: "CLIENT_RATE_LIMITS",
+	})
+	rateLimitHeaders := app.Strings(cli.StringsOpt{
+		Name:   "rate-limit-consumer-headers",
+		Value:  []string{"X-Api-Key", "X-Client-Id"},
+		Desc:   "Headers naming the consumers of client-rate-limits, the first naming one wins, when callers cannot authenticate. Other requests are limited by remote address.",
+		EnvVar: "RATE_LIMIT_CONSUMER_HEADERS",
+	})
+	rateLimitAddressHeader := app.String(cli.StringOpt{
+		Name:   "rate-limit-address-header",
+		Value:  "X-Forwarded-For",
+		Desc:   "Header in which the proxies in front of the service forward the address of the consumer. Requests limited by remote address use its first address, or the address of the connection without it. Empty always uses the address of the connection.",
+		EnvVar: "RATE_LIMIT_ADDRESS_HEADER",
+	})
+
+	orgCacheTTL := app.String(cli.StringOpt{
+		Name:   "org-cache-ttl",
+		Value:  "0s",
+		Desc:   "How long organisations read from public-concepts-api are cached in memory. 0s disables the cache.",
+		EnvVar: "ORG_CACHE_TTL",
+	})
+	orgCacheSize := app.Int(cli.IntOpt{
+		Name:   "org-cache-size",
+		Value:  10000,
+		Desc:   "Maximum number of organisations cached in memory. 0 means no limit.",
+		EnvVar: "ORG_CACHE_SIZE",
+	})
+	adminToken := app.String(cli.StringOpt{
+		Name:   "admin-token",
+		Value:  "",
+		Desc:   "Bearer token required by the /__admin endpoints. Empty disables them.",
+		EnvVar: "ADMIN_TOKEN",
+	})
+	authJWKSFile := app.String(cli.StringOpt{
+		Name:   "auth-jwks-file",
+		Value:  "",
+		Desc:   "JSON Web Key Set file the JWTs presented as bearer tokens are verified with. Empty rejects JWTs.",
+		EnvVar: "AUTH_JWKS_FILE",
+	})
+	authJWTIssuer := app.String(cli.StringOpt{
+		Name:   "auth-jwt-issuer",
+		Value:  "",
+		Desc:   "Issuer JWTs must have, empty accepts any",
+		EnvVar: "AUTH_JWT_ISSUER",
+	})
+	authJWTAudience := app.String(cli.StringOpt{
+		Name:   "auth-jwt-audience",
+		Value:  "",
+		Desc:   "Audience JWTs must have, empty accepts any",
+		EnvVar: "AUTH_JWT_AUDIENCE",
+	})
+	authAPIKeysFile := app.String(cli.StringOpt{
+		Name:   "auth-api-keys-file",
+		Value:  "",
+		Desc:   "YAML or JSON file listing the name, key and scopes of the API keys accepted in the X-Api-Key header",
+		EnvVar: "AUTH_API_KEYS_FILE",
+	})
+	authFieldScopes := app.Strings(cli.StringsOpt{
+		Name:   "auth-field-scopes",
+		Value:  []string{},
+		Desc:   "Fields only shown to callers with a scope, as scope=field field e.g. organisations:lei=leiCode. Other fields are shown to every caller.",
+		EnvVar: "AUTH_FIELD_SCOPES",
+	})
+	authRequired := app.Bool(cli.BoolOpt{
+		Name:   "auth-required",
+		Value:  false,
+		Desc:   "Reject requests without credentials to paths outside of auth-public-paths",
+		EnvVar: "AUTH_REQUIRED",
+	})
+	authPublicPaths := app.Strings(cli.StringsOpt{
+		Name:   "auth-public-paths",
+		Value:  []string{"/__"},
+		Desc:   "Prefixes of the paths served without authentication, by default the monitoring and admin endpoints, which have their own token",
+		EnvVar: "AUTH_PUBLIC_PATHS",
+	})
+
+	conceptEventsBrokers := app.Strings(cli.StringsOpt{
+		Name:   "concept-events-brokers",
+		Value:  []string{},
+		Desc:   "Kafka brokers to consume concept change notifications from, to keep the organisation cache up to date. Empty disables the consumer.",
+		EnvVar: "CONCEPT_EVENTS_BROKERS",
+	})
+	conceptEventsTopic := app.String(cli.StringOpt{
+		Name:   "concept-events-topic",
+		Value:  "ConceptEvents",
+		Desc:   "Kafka topic of the concept change notifications",
+		EnvVar: "CONCEPT_EVENTS_TOPIC",
+	})
+	conceptEventsRefresh := app.Bool(cli.BoolOpt{
+		Name:   "concept-events-refresh",
+		Value:  false,
+		Desc:   "Refresh the cached organisations affected by a change instead of invalidating them",
+		EnvVar: "CONCEPT_EVENTS_REFRESH",
+	})
+
+	webhookScope := app.String(cli.StringOpt{
+		Name:   "webhook-scope",
+		Value:  "",
+		Desc:   "Scope callers authenticated with a JWT or an API key need to use the webhook subscription API. Empty disables webhooks.",
+		EnvVar: "WEBHOOK_SCOPE",
+	})
+	webhookDB := app.String(cli.StringOpt{
+		Name:   "webhook-db",
+		Value:  "",
+		Desc:   "Path of the on-disk store keeping the webhook subscriptions, the versions seen and dead letters across restarts. Empty keeps them in memory.",
+		EnvVar: "WEBHOOK_DB",
+	})
+	webhookPollInterval := app.String(cli.StringOpt{
+		Name:   "webhook-poll-interval",
+		Value:  "5m",
+		Desc:   "How often subscribed organisations are read to detect changes. 0s only detects changes in organisations read for requests.",
+		EnvVar: "WEBHOOK_POLL_INTERVAL",
+	})
+	webhookTimeout := app.String(cli.StringOpt{
+		Name:   "webhook-timeout",
+		Value:  "10s",
+		Desc:   "Maximum duration of each attempt to deliver a webhook",
+		EnvVar: "WEBHOOK_TIMEOUT",
+	})
+	webhookMaxAttempts := app.Int(cli.IntOpt{
+		Name:   "webhook-max-attempts",
+		Value:  8,
+		Desc:   "How many times a webhook is sent before it is dead-lettered",
+		EnvVar: "WEBHOOK_MAX_ATTEMPTS",
+	})
+	webhookInitialBackoff := app.String(cli.StringOpt{
+		Name:   "webhook-initial-backoff",
+		Value:  "1s",
+		Desc:   "Wait before retrying a webhook the first time, doubling for each retry",
+		EnvVar: "WEBHOOK_INITIAL_BACKOFF",
+	})
+	webhookMaxBackoff := app.String(cli.StringOpt{
+		Name:   "webhook-max-backoff",
+		Value:  "5m",
+		Desc:   "Longest wait between retries of a webhook",
+		EnvVar: "WEBHOOK_MAX_BACKOFF",
+	})
+	webhookCallbackHosts := app.Strings(cli.StringsOpt{
+		Name:   "webhook-callback-hosts",
+		Value:  []string{},
+		Desc:   "Hosts the callback URLs of subscriptions may name, *.example.com naming every subdomain of example.com. Empty allows any host but those at internal addresses.",
+		EnvVar: "WEBHOOK_CALLBACK_HOSTS",
+	})
 
 	ftLogger := logger.NewUPPLogger(*appSystemCode, *logLevel)
 	ftLogger.Infof("[Startup] public-organisations-api is starting ")
 
 	app.Action = func() {
+		base := config{
+			AppSystemCode:               *appSystemCode,
+			Port:                        *port,
+			LogLevel:                    *logLevel,
+			CacheDuration:               *cacheDuration,
+			PublicConceptsAPIURL:        *publicConceptsAPIURL,
+			OrganisationSource:          *organisationSource,
+			OrganisationSourceFile:      *organisationSourceFile,
+			ContractValidation:          *contractValidation,
+			RecordDir:                   *recordDir,
+			ReplayDir:                   *replayDir,
+			AllowedTypes:                *allowedTypes,
+			DeniedTypes:                 *deniedTypes,
+			TracingExporter:             *tracingExporter,
+			HistoryDB:                   *historyDB,
+			ReadTimeout:                 *readTimeout,
+			ReadHeaderTimeout:           *readHeaderTimeout,
+			WriteTimeout:                *writeTimeout,
+			IdleTimeout:                 *idleTimeout,
+			PreStopDelay:                *preStopDelay,
+			ShutdownTimeout:             *shutdownTimeout,
+			HealthInterval:              *healthInterval,
+			HealthTimeout:               *healthTimeout,
+			HealthDegradedLatency:       *healthDegradedLatency,
+			CanaryUUID:                  *canaryUUID,
+			ClientTimeout:               *clientTimeout,
+			ClientDialTimeout:           *clientDialTimeout,
+			ClientTLSHandshakeTimeout:   *clientTLSHandshakeTimeout,
+			ClientResponseHeaderTimeout: *clientResponseHeaderTimeout,
+			ClientIdleConnTimeout:       *clientIdleConnTimeout,
+			ClientKeepAlive:             *clientKeepAlive,
+			ClientReuseConnections:      *clientReuseConnections,
+			ClientMaxIdleConnsPerHost:   *clientMaxIdleConnsPerHost,
+			ClientMaxConnsPerHost:       *clientMaxConnsPerHost,
+			ClientHTTP2:                 *clientHTTP2,
+			ClientProxy:                 *clientProxy,
+			RateLimit:                   *rateLimit,
+			ClientRateLimits:            *clientRateLimits,
+			RateLimitConsumerHeaders:    *rateLimitHeaders,
+			RateLimitAddressHeader:      *rateLimitAddressHeader,
+			OrgCacheTTL:                 *orgCacheTTL,
+			OrgCacheSize:                *orgCacheSize,
+			AdminToken:                  *adminToken,
+			AuthJWKSFile:                *authJWKSFile,
+			AuthJWTIssuer:               *authJWTIssuer,
+			AuthJWTAudience:             *authJWTAudience,
+			AuthAPIKeysFile:             *authAPIKeysFile,
+			AuthFieldScopes:             *authFieldScopes,
+			AuthRequired:                *authRequired,
+			AuthPublicPaths:             *authPublicPaths,
+			ConceptEventsBrokers:        *conceptEventsBrokers,
+			ConceptEventsTopic:          *conceptEventsTopic,
+			ConceptEventsRefresh:        *conceptEventsRefresh,
+			WebhookScope:                *webhookScope,
+			WebhookDB:                   *webhookDB,
+			WebhookPollInterval:         *webhookPollInterval,
+			WebhookTimeout:              *webhookTimeout,
+			WebhookMaxAttempts:          *webhookMaxAttempts,
+			WebhookInitialBackoff:       *webhookInitialBackoff,
+			WebhookMaxBackoff:           *webhookMaxBackoff,
+			WebhookCallbackHosts:        *webhookCallbackHosts,
+		}
+		cfg, data, err := loadConfig(base, *configFile)
+		if err != nil {
+			ftLogger.Fatalf("Failed to load configuration: %v", err)
+		}
+		level, err := cfg.logLevel()
+		if err != nil {
+			ftLogger.Fatalf("Failed to parse log level: %v", err)
+		}
+		ftLogger.SetLevel(level)
+		ftLogger.WithField("config", cfg.redacted()).Info("[Startup] Effective configuration")
+
+		ftLogger.Infof("public-organisations-api will listen on port: %s", cfg.Port)
+		shutdownTracing, err := setupTracing(context.Background(), cfg.TracingExporter, cfg.AppSystemCode)
+		if err != nil {
+			ftLogger.Fatalf("Failed to set up tracing: %v", err)
+		}
+		defer shutdownTracing(context.Background())
+
+		handlerOpts := []organisations.HandlerOption{
+			organisations.WithTypeFilter(organisations.NewTypeFilter(cfg.AllowedTypes, cfg.DeniedTypes)),
+		}
+		source, err := cfg.organisationSource()
+		if err != nil {
+			ftLogger.Fatalf("Failed to load organisation source: %v", err)
+		}
+		if source != nil {
+			handlerOpts = append(handlerOpts, organisations.WithSource(source))
+		}
+		contract, contractV2, err := cfg.contractValidators()
+		if err != nil {
+			ftLogger.Fatalf("Failed to set up contract validation: %v", err)
+		}
+		if contract != nil {
+			handlerOpts = append(handlerOpts, organisations.WithContractValidator(contract), organisations.WithContractValidatorV2(contractV2))
+		}
+		if cfg.HistoryDB != "" {
+			store, err := organisations.NewBoltHistoryStore(cfg.HistoryDB)
+			if err != nil {
+				ftLogger.Fatalf("Failed to open history store: %v", err)
+			}
+			defer store.Close()
+			handlerOpts = append(handlerOpts, organisations.WithHistoryStore(store))
+		}
+		cache, err := cfg.organisationCache()
+		if err != nil {
+			ftLogger.Fatalf("Failed to parse organisation cache options: %v", err)
+		}
+		if cache != nil {
+			handlerOpts = append(handlerOpts, organisations.WithCache(cache))
+		} else if len(cfg.ConceptEventsBrokers) > 0 {
+			ftLogger.Warn("Concept change notifications are not consumed without an organisation cache")
+			cfg.ConceptEventsBrokers = nil
+		}
+
+		srvCfg, err := cfg.serverConfig()
+		if err != nil {
+			ftLogger.Fatalf("Failed to parse server options: %v", err)
+		}
+		cacheControl, err := cfg.cacheControlHeader()
+		if err != nil {
+			ftLogger.Fatalf("Failed to parse cache duration: %v", err)
+		}
+		organisations.SetCacheControlHeader(cacheControl)
+
+		clientCfg, err := cfg.clientConfig()
+		if err != nil {
+			ftLogger.Fatalf("Failed to parse HTTP client options: %v", err)
+		}
+		clientMetrics := newClientMetrics(prometheus.DefaultRegisterer)
+		httpClient, err := newHTTPClient(clientCfg, clientMetrics)
+		if err != nil {
+			ftLogger.Fatalf("Failed to create HTTP client: %v", err)
+		}
+		client := &reloadableClient{}
+		client.set(httpClient)
+
+		defaultLimit, clientLimits, err := cfg.rateLimits()
+		if err != nil {
+			ftLogger.Fatalf("Failed to parse rate limits: %v", err)
+		}
+		limiter := organisations.NewRateLimiter(cfg.rateLimitConsumerHeaders(), cfg.RateLimitAddressHeader, defaultLimit, clientLimits, prometheus.DefaultRegisterer)
+
+		if *configFile != "" {
+			r := &reloader{
+				path:    *configFile,
+				base:    base,
+				current: cfg,
+				data:    data,
+				logger:  ftLogger,
+				client:  client,
+				metrics: clientMetrics,
+				limiter: limiter,
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := r.watch(ctx); err != nil {
+				ftLogger.Fatalf("Failed to watch config file: %v", err)
+			}
+		}
+
+		auth, err := cfg.authenticator(ftLogger, prometheus.DefaultRegisterer)
+		if err != nil {
+			ftLogger.Fatalf("Failed to set up authentication: %v", err)
+		}
+		if auth != nil {
+			handlerOpts = append(handlerOpts, organisations.WithAuthenticator(auth))
+		}
 
-		ftLogger.Infof("public-organisations-api will listen on port: %s", *port)
-		runServer(*port, *cacheDuration, *publicConceptsAPIURL, ftLogger)
+		upstream, err := cfg.upstreamClient(client, ftLogger)
+		if err != nil {
+			ftLogger.Fatalf("Failed to set up recording: %v", err)
+		}
 
+		runServer(srvCfg, upstream, limiter, auth, cfg.PublicConceptsAPIURL, ftLogger, handlerOpts...)
 	}
+	registerCommands(app, ftLogger)
 	ftLogger.Infof("Application started with args %s", os.Args)
 	app.Run(os.Args)
 }
 
-func runServer(port string, cacheDuration string, publicConceptsAPIURL string, ftLogger *logger.UPPLogger) {
-	if duration, durationErr := time.ParseDuration(cacheDuration); durationErr != nil {
-		ftLogger.Fatalf("Failed to parse cache duration string, %v", durationErr)
-	} else {
-		organisations.CacheControlHeader = fmt.Sprintf("max-age=%s, public", strconv.FormatFloat(duration.Seconds(), 'f', 0, 64))
-	}
-
+func runServer(cfg serverConfig, client organisations.HTTPClient, limiter *organisations.RateLimiter, auth *organisations.Authenticator, publicConceptsAPIURL string, ftLogger *logger.UPPLogger, handlerOpts ...organisations.HandlerOption) {
 	servicesRouter := mux.NewRouter()
 
-	handler := organisations.NewHandler(&httpClient, publicConceptsAPIURL, ftLogger)
+	prometheus.MustRegister(newGoMetricsCollector(metrics.DefaultRegistry))
+	handlerOpts = append(handlerOpts, organisations.WithMetrics(organisations.NewMetrics(prometheus.DefaultRegisterer)))
+	var webhooks *organisations.Webhooks
+	if cfg.webhookScope != "" {
+		var store organisations.WebhookStore = organisations.NewMemoryWebhookStore()
+		if cfg.webhookDB != "" {
+			bolt, err := organisations.NewBoltWebhookStore(cfg.webhookDB)
+			if err != nil {
+				ftLogger.Fatalf("Failed to open webhook store: %v", err)
+			}
+			store = bolt
+		}
+		defer store.Close()
+		var err error
+		webhooks, err = organisations.NewWebhooks(organisations.NewWebhookClient(cfg.webhookTimeout, cfg.webhooks.CallbackHosts), store, cfg.webhooks, ftLogger, prometheus.DefaultRegisterer)
+		if err != nil {
+			ftLogger.Fatalf("Failed to load webhook subscriptions: %v", err)
+		}
+		handlerOpts = append(handlerOpts, organisations.WithWebhooks(webhooks))
+	}
+	handler := organisations.NewHandler(client, publicConceptsAPIURL, ftLogger, handlerOpts...)
 
 	// Healthchecks and standards first
-	healthCheck := fthealth.TimedHealthCheck{
-		HealthCheck: fthealth.HealthCheck{
-			SystemCode:  "public-org-api",
-			Name:        "PublicOrganisationsRead Healthcheck",
-			Description: "Checks for the downstream services' health",
-			Checks:      []fthealth.Check{handler.HealthCheck()},
-		},
-		Timeout: 10 * time.Second,
+	healthCheck := fthealth.HealthCheck{
+		SystemCode:  "public-org-api",
+		Name:        "PublicOrganisationsRead Healthcheck",
+		Description: "Checks for the downstream services' health",
+		Checks:      []fthealth.Check{handler.HealthCheck()},
 	}
+	if cfg.canaryUUID != "" {
+		healthCheck.Checks = append(healthCheck.Checks, handler.CanaryCheck(cfg.canaryUUID))
+	}
+	poller := organisations.NewHealthPoller(healthCheck, cfg.healthInterval, cfg.healthTimeout, cfg.healthDegradedLatency)
+	// A broken canary affects every instance alike, so it alerts without taking instances out of service
+	poller.ExcludeFromGTG(organisations.CanaryCheckID)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go poller.Run(ctx)
 
-	servicesRouter.HandleFunc("/__health", fthealth.Handler(healthCheck))
+	servicesRouter.HandleFunc("/__health", poller.HealthHandler)
+	servicesRouter.HandleFunc("/__api", organisations.OpenAPIHandler(servicesRouter, ftLogger))
 
 	// Then API specific ones:
 	handler.RegisterHandlers(servicesRouter)
+	if cfg.adminToken != "" {
+		handler.RegisterAdminHandlers(servicesRouter, cfg.adminToken)
+	}
+	if webhooks != nil {
+		handler.RegisterWebhookHandlers(servicesRouter, cfg.webhookScope)
+		go webhooks.Run(ctx, &handler)
+	}
+	if len(cfg.conceptEventsBrokers) > 0 {
+		source, err := organisations.NewKafkaSource(cfg.conceptEventsBrokers, cfg.conceptEventsTopic)
+		if err != nil {
+			ftLogger.Fatalf("Failed to consume concept change notifications: %v", err)
+		}
+		defer func() {
+			cancel()
+			source.Close()
+		}()
+		go organisations.NewChangeConsumer(&handler, source, cfg.conceptEventsRefresh).Run(ctx)
+	}
+	// Callers are authenticated before they are limited, so that authenticated callers are limited by who they are
+	servicesRouter.Use(tracingMiddleware)
+	if auth != nil {
+		servicesRouter.Use(auth.Middleware)
+	}
+	servicesRouter.Use(limiter.Middleware)
 
 	var monitoringRouter http.Handler = servicesRouter
 	monitoringRouter = httphandlers.TransactionAwareRequestLoggingHandler(ftLogger, monitoringRouter)
@@ -115,11 +660,10 @@ func runServer(port string, cacheDuration string, publicConceptsAPIURL string, f
 	http.HandleFunc(status.PingPathDW, status.PingHandler)
 	http.HandleFunc(status.BuildInfoPath, status.BuildInfoHandler)
 	http.HandleFunc(status.BuildInfoPathDW, status.BuildInfoHandler)
-	servicesRouter.HandleFunc(status.GTGPath, status.NewGoodToGoHandler(handler.GTG))
+	http.Handle("/metrics", promhttp.Handler())
+	gate := &shutdownGate{}
+	servicesRouter.HandleFunc(status.GTGPath, status.NewGoodToGoHandler(gate.gtg(poller.GTG)))
 	http.Handle("/", monitoringRouter)
 
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		ftLogger.Fatalf("Unable to start server: %v", err)
-	}
-
+	serve(cfg, http.DefaultServeMux, gate, ftLogger)
 }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// clientConfig describes the HTTP client used to call public-concepts-api
+type clientConfig struct {
+	// timeout bounds the whole request, including reading the body. Zero means no timeout.
+	timeout               time.Duration
+	dialTimeout           time.Duration
+	tlsHandshakeTimeout   time.Duration
+	responseHeaderTimeout time.Duration
+	idleConnTimeout       time.Duration
+	// keepAlive is the TCP keep-alive period. Zero or less disables TCP keep-alives.
+	keepAlive time.Duration
+	// reuseConnections keeps connections open for later requests. Otherwise each one is closed after its response.
+	reuseConnections    bool
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	http2               bool
+	// proxy is the URL of the proxy requests go through. Empty connects directly.
+	proxy string
+}
+
+func newHTTPClient(cfg clientConfig, metrics *clientMetrics) (*http.Client, error) {
+	dialer := &net.Dialer{
+		Timeout:   cfg.dialTimeout,
+		KeepAlive: cfg.keepAlive,
+	}
+	if cfg.keepAlive <= 0 {
+		dialer.KeepAlive = -1
+	}
+
+	transport := &http.Transport{
+		DialContext:           metrics.instrumentDial(dialer.DialContext),
+		TLSHandshakeTimeout:   cfg.tlsHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.responseHeaderTimeout,
+		IdleConnTimeout:       cfg.idleConnTimeout,
+		MaxIdleConnsPerHost:   cfg.maxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.maxConnsPerHost,
+		DisableKeepAlives:     !cfg.reuseConnections,
+		ForceAttemptHTTP2:     cfg.http2,
+	}
+	if !cfg.http2 {
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	if cfg.proxy != "" {
+		proxyURL, err := url.Parse(cfg.proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parsing proxy URL %s: %w", cfg.proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return &http.Client{
+		Timeout:   cfg.timeout,
+		Transport: metrics.instrumentRoundTripper(transport),
+	}, nil
+}
+
+// clientMetrics are the Prometheus collectors describing the connections of the HTTP client, by host
+type clientMetrics struct {
+	open     *prometheus.GaugeVec
+	dials    *prometheus.CounterVec
+	requests *prometheus.CounterVec
+}
+
+func newClientMetrics(registerer prometheus.Registerer) *clientMetrics {
+	m := &clientMetrics{
+		open: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "public_organisations_api",
+			Name:      "upstream_connections_open",
+			Help:      "Connections currently open to upstream services by dialled address.",
+		}, []string{"host"}),
+		dials: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "public_organisations_api",
+			Name:      "upstream_connection_dials_total",
+			Help:      "Connections dialled to upstream services by dialled address and result.",
+		}, []string{"host", "result"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "public_organisations_api",
+			Name:      "upstream_requests_total",
+			Help:      "Requests to upstream services by host and whether they used a new or reused connection.",
+		}, []string{"host", "connection"}),
+	}
+	registerer.MustRegister(m.open, m.dials, m.requests)
+	return m
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (m *clientMetrics) instrumentDial(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			m.dials.WithLabelValues(addr, "error").Inc()
+			return nil, err
+		}
+		m.dials.WithLabelValues(addr, "success").Inc()
+		open := m.open.WithLabelValues(addr)
+		open.Inc()
+		return &trackedConn{Conn: conn, onClose: open.Dec}, nil
+	}
+}
+
+func (m *clientMetrics) instrumentRoundTripper(next *http.Transport) http.RoundTripper {
+	return &instrumentedTransport{next: next, metrics: m}
+}
+
+// instrumentedTransport counts whether each request used a new or a reused connection
+type instrumentedTransport struct {
+	next    *http.Transport
+	metrics *clientMetrics
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			connection := "new"
+			if info.Reused {
+				connection = "reused"
+			}
+			t.metrics.requests.WithLabelValues(host, connection).Inc()
+		},
+	}
+	return t.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+func (t *instrumentedTransport) CloseIdleConnections() {
+	t.next.CloseIdleConnections()
+}
+
+// reloadableClient is an HTTP client that can be replaced while requests are in flight
+type reloadableClient struct {
+	current atomic.Pointer[http.Client]
+}
+
+func (c *reloadableClient) Do(req *http.Request) (*http.Response, error) {
+	return c.current.Load().Do(req)
+}
+
+// set replaces the client, closing the idle connections of the previous one. Requests already sent complete on it.
+func (c *reloadableClient) set(client *http.Client) {
+	if previous := c.current.Swap(client); previous != nil {
+		previous.CloseIdleConnections()
+	}
+}
+
+// trackedConn calls onClose the first time the connection is closed
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	onClose func()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(c.onClose)
+	return c.Conn.Close()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/public-organisations-api/v3/organisations"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testClientConfig(t *testing.T) clientConfig {
+	cfg, err := testConfig().clientConfig()
+	require.NoError(t, err)
+	return cfg
+}
+
+func transportOf(t *testing.T, client *http.Client) *http.Transport {
+	instrumented, ok := client.Transport.(*instrumentedTransport)
+	require.True(t, ok, "the transport is instrumented")
+	return instrumented.next
+}
+
+func get(t *testing.T, client organisations.HTTPClient, url string) {
+	req, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	cfg := testClientConfig(t)
+	client, err := newHTTPClient(cfg, newClientMetrics(prometheus.NewRegistry()))
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, client.Timeout, "requests have a timeout by default")
+	transport := transportOf(t, client)
+	assert.False(t, transport.DisableKeepAlives)
+	assert.Equal(t, 60*time.Second, transport.IdleConnTimeout)
+	assert.Equal(t, 128, transport.MaxIdleConnsPerHost)
+	assert.NotNil(t, transport.TLSNextProto, "HTTP/2 is off unless asked for")
+	assert.Empty(t, transport.TLSNextProto)
+	assert.Nil(t, transport.Proxy)
+
+	cfg.keepAlive = 0
+	client, err = newHTTPClient(cfg, newClientMetrics(prometheus.NewRegistry()))
+	require.NoError(t, err)
+	assert.False(t, transportOf(t, client).DisableKeepAlives, "disabling TCP keep-alives does not stop connections being reused")
+
+	cfg.reuseConnections = false
+	cfg.http2 = true
+	cfg.proxy = "http://proxy.example.com:3128"
+	client, err = newHTTPClient(cfg, newClientMetrics(prometheus.NewRegistry()))
+	require.NoError(t, err)
+	transport = transportOf(t, client)
+	assert.True(t, transport.DisableKeepAlives)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Nil(t, transport.TLSNextProto)
+	proxyURL, err := transport.Proxy(httptest.NewRequest("GET", "http://public-concepts-api:8080/concepts", nil))
+	require.NoError(t, err)
+	assert.Equal(t, &url.URL{Scheme: "http", Host: "proxy.example.com:3128"}, proxyURL)
+
+	cfg.proxy = "http://proxy.example.com:port"
+	_, err = newHTTPClient(cfg, newClientMetrics(prometheus.NewRegistry()))
+	assert.Error(t, err)
+}
+
+func TestClientMetricsCountConnections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	host := server.Listener.Addr().String()
+
+	for name, test := range map[string]struct {
+		reuseConnections bool
+		expectedNew      float64
+		expectedReused   float64
+		expectedOpen     float64
+	}{
+		"reused":     {true, 1, 2, 1},
+		"not reused": {false, 3, 0, 0},
+	} {
+		metrics := newClientMetrics(prometheus.NewRegistry())
+		cfg := testClientConfig(t)
+		cfg.reuseConnections = test.reuseConnections
+		client, err := newHTTPClient(cfg, metrics)
+		require.NoError(t, err, name)
+
+		for i := 0; i < 3; i++ {
+			get(t, client, server.URL)
+		}
+		assert.Equal(t, test.expectedNew, testutil.ToFloat64(metrics.requests.WithLabelValues(host, "new")), name)
+		assert.Equal(t, test.expectedReused, testutil.ToFloat64(metrics.requests.WithLabelValues(host, "reused")), name)
+		assert.Equal(t, test.expectedNew, testutil.ToFloat64(metrics.dials.WithLabelValues(host, "success")), name)
+		assert.Eventually(t, func() bool {
+			return testutil.ToFloat64(metrics.open.WithLabelValues(host)) == test.expectedOpen
+		}, time.Second, 10*time.Millisecond, name)
+
+		client.CloseIdleConnections()
+		assert.Eventually(t, func() bool {
+			return testutil.ToFloat64(metrics.open.WithLabelValues(host)) == 0
+		}, time.Second, 10*time.Millisecond, "%s: closed connections are no longer open", name)
+	}
+
+	metrics := newClientMetrics(prometheus.NewRegistry())
+	client, err := newHTTPClient(testClientConfig(t), metrics)
+	require.NoError(t, err)
+	server.Close()
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.dials.WithLabelValues(host, "error")))
+	assert.Zero(t, testutil.ToFloat64(metrics.open.WithLabelValues(host)))
+}
+
+func TestReloadableClientClosesTheIdleConnectionsOfThePreviousClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	host := server.Listener.Addr().String()
+
+	metrics := newClientMetrics(prometheus.NewRegistry())
+	first, err := newHTTPClient(testClientConfig(t), metrics)
+	require.NoError(t, err)
+	client := &reloadableClient{}
+	client.set(first)
+	get(t, client, server.URL)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.open.WithLabelValues(host)))
+
+	cfg := testClientConfig(t)
+	cfg.timeout = 5 * time.Second
+	second, err := newHTTPClient(cfg, metrics)
+	require.NoError(t, err)
+	client.set(second)
+	assert.Same(t, second, client.current.Load())
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.open.WithLabelValues(host)) == 0
+	}, time.Second, 10*time.Millisecond, "the idle connection of the previous client is closed")
+
+	get(t, client, server.URL)
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.requests.WithLabelValues(host, "new")), "the new client dials its own connection")
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.open.WithLabelValues(host)))
+}
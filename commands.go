@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/Financial-Times/public-organisations-api/v3/organisations"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	cli "github.com/jawher/mow.cli"
+)
+
+const commandTimeout = 30 * time.Second
+
+// registerCommands adds the subcommands support engineers use to fetch and transform organisations without
+// running the server
+func registerCommands(app *cli.Cli, ftLogger *logger.UPPLogger) {
+	app.Command("get", "Fetch an organisation from public-concepts-api and print its public JSON", func(cmd *cli.Cmd) {
+		cmd.Spec = "[--concepts-url] [--show-label-details] UUID"
+		conceptsURL := cmd.String(cli.StringOpt{
+			Name:   "concepts-url",
+			Value:  "http://localhost:8081",
+			Desc:   "Public concepts API endpoint URL",
+			EnvVar: "CONCEPTS_API",
+		})
+		labelDetails := cmd.Bool(cli.BoolOpt{
+			Name: "show-label-details",
+			Desc: "Include the labelDetails of the organisation",
+		})
+		uuid := cmd.String(cli.StringArg{Name: "UUID", Desc: "UUID of the organisation"})
+
+		cmd.Action = func() {
+			ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+			defer cancel()
+			h := organisations.NewHandler(&http.Client{Timeout: commandTimeout}, *conceptsURL, ftLogger)
+			org, found, err := h.Organisation(ctx, *uuid, transactionidutils.NewTransactionID())
+			if err != nil {
+				exitWithError(fmt.Errorf("fetching organisation %s: %w", *uuid, err))
+			}
+			if !found {
+				exitWithError(fmt.Errorf("organisation %s not found", *uuid))
+			}
+			if err := printOrganisation(os.Stdout, org, *labelDetails); err != nil {
+				exitWithError(err)
+			}
+		}
+	})
+
+	app.Command("transform", "Transform a public-concepts-api response and print the public JSON of the organisation", func(cmd *cli.Cmd) {
+		cmd.Spec = "[--show-label-details] [FILE]"
+		labelDetails := cmd.Bool(cli.BoolOpt{
+			Name: "show-label-details",
+			Desc: "Include the labelDetails of the organisation",
+		})
+		file := cmd.String(cli.StringArg{Name: "FILE", Value: "-", Desc: "File of the concept, - reads standard input"})
+
+		cmd.Action = func() {
+			org, err := transformFile(*file, os.Stdin, ftLogger)
+			if err != nil {
+				exitWithError(err)
+			}
+			if err := printOrganisation(os.Stdout, org, *labelDetails); err != nil {
+				exitWithError(err)
+			}
+		}
+	})
+
+	app.Command("diff", "Print the fields that differ between the transforms of two public-concepts-api responses, exiting with 1 when there are any", func(cmd *cli.Cmd) {
+		cmd.Spec = "PREVIOUS CURRENT"
+		previousFile := cmd.String(cli.StringArg{Name: "PREVIOUS", Desc: "File of the previous concept, - reads standard input"})
+		currentFile := cmd.String(cli.StringArg{Name: "CURRENT", Desc: "File of the current concept, - reads standard input"})
+
+		cmd.Action = func() {
+			changes, err := diffFiles(*previousFile, *currentFile, os.Stdin, ftLogger)
+			if err != nil {
+				exitWithError(err)
+			}
+			if err := printJSON(os.Stdout, changes); err != nil {
+				exitWithError(err)
+			}
+			if len(changes) > 0 {
+				cli.Exit(1)
+			}
+		}
+	})
+}
+
+// transformFile transforms the concept in file, or read from stdin when file is -, as the API would
+func transformFile(file string, stdin io.Reader, ftLogger *logger.UPPLogger) (organisations.Organisation, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return organisations.Organisation{}, fmt.Errorf("reading %s: %w", file, err)
+	}
+	concept := organisations.ConceptApiResponse{}
+	if err := json.Unmarshal(data, &concept); err != nil {
+		return organisations.Organisation{}, fmt.Errorf("%s is not a public-concepts-api concept: %w", file, err)
+	}
+
+	source := organisations.NewMemoryOrganisationSource(concept)
+	h := organisations.NewHandler(nil, "", ftLogger, organisations.WithSource(source))
+	org, found, err := h.Organisation(context.Background(), source.UUIDs()[0], transactionidutils.NewTransactionID())
+	if err != nil {
+		return organisations.Organisation{}, fmt.Errorf("transforming %s: %w", file, err)
+	}
+	if !found {
+		return organisations.Organisation{}, fmt.Errorf("%s is not an organisation", file)
+	}
+	return org, nil
+}
+
+func diffFiles(previousFile string, currentFile string, stdin io.Reader, ftLogger *logger.UPPLogger) ([]organisations.FieldChange, error) {
+	if previousFile == "-" && currentFile == "-" {
+		return nil, fmt.Errorf("only one of the files can be read from standard input")
+	}
+	previous, err := transformFile(previousFile, stdin, ftLogger)
+	if err != nil {
+		return nil, err
+	}
+	current, err := transformFile(currentFile, stdin, ftLogger)
+	if err != nil {
+		return nil, err
+	}
+	return organisations.DiffOrganisations(previous, current)
+}
+
+// printOrganisation prints the organisation as the API returns it
+func printOrganisation(w io.Writer, org organisations.Organisation, labelDetails bool) error {
+	if !labelDetails {
+		org.LabelDetails = nil
+	}
+	return printJSON(w, org)
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func exitWithError(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	cli.Exit(1)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/Financial-Times/public-organisations-api/v3/organisations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConcept = `{
+	"id": "http://www.ft.com/thing/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6",
+	"apiUrl": "http://api.ft.com/concepts/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6",
+	"type": "http://www.ft.com/ontology/organisation/Organisation",
+	"prefLabel": "Google Inc"
+}`
+
+func TestTransformFile(t *testing.T) {
+	log := logger.NewUPPInfoLogger("tests")
+	file := filepath.Join(t.TempDir(), "concept.json")
+	require.NoError(t, os.WriteFile(file, []byte(testConcept), 0600))
+
+	org, err := transformFile(file, nil, log)
+	require.NoError(t, err)
+	assert.Equal(t, "http://api.ft.com/things/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", org.ID)
+	assert.Equal(t, "http://api.ft.com/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", org.APIURL)
+
+	fromStdin, err := transformFile("-", strings.NewReader(testConcept), log)
+	require.NoError(t, err)
+	assert.Equal(t, org, fromStdin)
+
+	var out bytes.Buffer
+	require.NoError(t, printOrganisation(&out, org, false))
+	assert.Contains(t, out.String(), `"prefLabel": "Google Inc"`)
+
+	person := strings.Replace(testConcept, "organisation/Organisation", "person/Person", 1)
+	_, err = transformFile("-", strings.NewReader(person), log)
+	assert.EqualError(t, err, "- is not an organisation")
+	_, err = transformFile("-", strings.NewReader("nintendo"), log)
+	assert.Error(t, err)
+}
+
+func TestDiffFiles(t *testing.T) {
+	log := logger.NewUPPInfoLogger("tests")
+	file := filepath.Join(t.TempDir(), "concept.json")
+	require.NoError(t, os.WriteFile(file, []byte(testConcept), 0600))
+
+	changes, err := diffFiles(file, "-", strings.NewReader(strings.Replace(testConcept, "Google Inc", "Alphabet Inc", 1)), log)
+	require.NoError(t, err)
+	assert.Equal(t, []organisations.FieldChange{{Field: "prefLabel", Previous: "Google Inc", Current: "Alphabet Inc"}}, changes)
+
+	changes, err = diffFiles(file, file, nil, log)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = diffFiles("-", "-", nil, log)
+	assert.Error(t, err)
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/Financial-Times/public-organisations-api/v3/organisations"
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// apiDefinition is the Swagger definition of the API, the contract organisations are validated against
+//
+//go:embed _ft/api.yml
+var apiDefinition []byte
+
+// config is the effective configuration of the service: the command line options, overridden by the config file.
+// Fields are named after the command line options in both JSON and YAML config files.
+type config struct {
+	AppSystemCode               string   `json:"app-system-code" yaml:"app-system-code"`
+	Port                        string   `json:"port" yaml:"port"`
+	LogLevel                    string   `json:"log-level" yaml:"log-level"`
+	CacheDuration               string   `json:"cache-duration" yaml:"cache-duration"`
+	PublicConceptsAPIURL        string   `json:"publicConceptsApiURL" yaml:"publicConceptsApiURL"`
+	OrganisationSource          string   `json:"organisation-source" yaml:"organisation-source"`
+	OrganisationSourceFile      string   `json:"organisation-source-file" yaml:"organisation-source-file"`
+	ContractValidation          string   `json:"contract-validation" yaml:"contract-validation"`
+	RecordDir                   string   `json:"record-dir" yaml:"record-dir"`
+	ReplayDir                   string   `json:"replay-dir" yaml:"replay-dir"`
+	AllowedTypes                []string `json:"allowed-types" yaml:"allowed-types"`
+	DeniedTypes                 []string `json:"denied-types" yaml:"denied-types"`
+	TracingExporter             string   `json:"tracing-exporter" yaml:"tracing-exporter"`
+	HistoryDB                   string   `json:"history-db" yaml:"history-db"`
+	ReadTimeout                 string   `json:"read-timeout" yaml:"read-timeout"`
+	ReadHeaderTimeout           string   `json:"read-header-timeout" yaml:"read-header-timeout"`
+	WriteTimeout                string   `json:"write-timeout" yaml:"write-timeout"`
+	IdleTimeout                 string   `json:"idle-timeout" yaml:"idle-timeout"`
+	PreStopDelay                string   `json:"pre-stop-delay" yaml:"pre-stop-delay"`
+	ShutdownTimeout             string   `json:"shutdown-timeout" yaml:"shutdown-timeout"`
+	HealthInterval              string   `json:"health-interval" yaml:"health-interval"`
+	HealthTimeout               string   `json:"health-timeout" yaml:"health-timeout"`
+	HealthDegradedLatency       string   `json:"health-degraded-latency" yaml:"health-degraded-latency"`
+	CanaryUUID                  string   `json:"canary-uuid" yaml:"canary-uuid"`
+	ClientTimeout               string   `json:"client-timeout" yaml:"client-timeout"`
+	ClientDialTimeout           string   `json:"client-dial-timeout" yaml:"client-dial-timeout"`
+	ClientTLSHandshakeTimeout   string   `json:"client-tls-handshake-timeout" yaml:"client-tls-handshake-timeout"`
+	ClientResponseHeaderTimeout string   `json:"client-response-header-timeout" yaml:"client-response-header-timeout"`
+	ClientIdleConnTimeout       string   `json:"client-idle-conn-timeout" yaml:"client-idle-conn-timeout"`
+	ClientKeepAlive             string   `json:"client-keep-alive" yaml:"client-keep-alive"`
+	ClientReuseConnections      bool     `json:"client-reuse-connections" yaml:"client-reuse-connections"`
+	ClientMaxIdleConnsPerHost   int      `json:"client-max-idle-conns-per-host" yaml:"client-max-idle-conns-per-host"`
+	ClientMaxConnsPerHost       int      `json:"client-max-conns-per-host" yaml:"client-max-conns-per-host"`
+	ClientHTTP2                 bool     `json:"client-http2" yaml:"client-http2"`
+	ClientProxy                 string   `json:"client-proxy" yaml:"client-proxy"`
+	RateLimit                   string   `json:"rate-limit" yaml:"rate-limit"`
+	ClientRateLimits            []string `json:"client-rate-limits" yaml:"client-rate-limits"`
+	RateLimitConsumerHeaders    []string `json:"rate-limit-consumer-headers" yaml:"rate-limit-consumer-headers"`
+	RateLimitAddressHeader      string   `json:"rate-limit-address-header" yaml:"rate-limit-address-header"`
+	OrgCacheTTL                 string   `json:"org-cache-ttl" yaml:"org-cache-ttl"`
+	OrgCacheSize                int      `json:"org-cache-size" yaml:"org-cache-size"`
+	AdminToken                  string   `json:"admin-token" yaml:"admin-token"`
+	AuthJWKSFile                string   `json:"auth-jwks-file" yaml:"auth-jwks-file"`
+	AuthJWTIssuer               string   `json:"auth-jwt-issuer" yaml:"auth-jwt-issuer"`
+	AuthJWTAudience             string   `json:"auth-jwt-audience" yaml:"auth-jwt-audience"`
+	AuthAPIKeysFile             string   `json:"auth-api-keys-file" yaml:"auth-api-keys-file"`
+	AuthFieldScopes             []string `json:"auth-field-scopes" yaml:"auth-field-scopes"`
+	AuthRequired                bool     `json:"auth-required" yaml:"auth-required"`
+	AuthPublicPaths             []string `json:"auth-public-paths" yaml:"auth-public-paths"`
+	ConceptEventsBrokers        []string `json:"concept-events-brokers" yaml:"concept-events-brokers"`
+	ConceptEventsTopic          string   `json:"concept-events-topic" yaml:"concept-events-topic"`
+	ConceptEventsRefresh        bool     `json:"concept-events-refresh" yaml:"concept-events-refresh"`
+	WebhookScope                string   `json:"webhook-scope" yaml:"webhook-scope"`
+	WebhookDB                   string   `json:"webhook-db" yaml:"webhook-db"`
+	WebhookPollInterval         string   `json:"webhook-poll-interval" yaml:"webhook-poll-interval"`
+	WebhookTimeout              string   `json:"webhook-timeout" yaml:"webhook-timeout"`
+	WebhookMaxAttempts          int      `json:"webhook-max-attempts" yaml:"webhook-max-attempts"`
+	WebhookInitialBackoff       string   `json:"webhook-initial-backoff" yaml:"webhook-initial-backoff"`
+	WebhookMaxBackoff           string   `json:"webhook-max-backoff" yaml:"webhook-max-backoff"`
+	WebhookCallbackHosts        []string `json:"webhook-callback-hosts" yaml:"webhook-callback-hosts"`
+}
+
+// configSettleDelay is how long the config file must stay unchanged before it is reloaded
+const configSettleDelay = 250 * time.Millisecond
+
+// reloadableOptions are the options a changed config file applies without a restart
+var reloadableOptions = map[string]bool{
+	"log-level":                      true,
+	"cache-duration":                 true,
+	"rate-limit":                     true,
+	"client-rate-limits":             true,
+	"client-timeout":                 true,
+	"client-dial-timeout":            true,
+	"client-tls-handshake-timeout":   true,
+	"client-response-header-timeout": true,
+	"client-idle-conn-timeout":       true,
+	"client-keep-alive":              true,
+	"client-reuse-connections":       true,
+	"client-max-idle-conns-per-host": true,
+	"client-max-conns-per-host":      true,
+	"client-http2":                   true,
+	"client-proxy":                   true,
+}
+
+// loadConfig overrides base with the options set in the YAML or JSON file at path. Files ending in .json are
+// read as JSON, anything else as YAML. Unknown options are an error.
+func loadConfig(base config, path string) (config, []byte, error) {
+	if path == "" {
+		return base, nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config{}, nil, fmt.Errorf("reading config file: %w", err)
+	}
+	cfg, err := parseConfig(base, data, strings.EqualFold(filepath.Ext(path), ".json"))
+	if err != nil {
+		return config{}, nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return cfg, data, nil
+}
+
+func parseConfig(base config, data []byte, isJSON bool) (config, error) {
+	cfg := base
+	if isJSON {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		return cfg, decoder.Decode(&cfg)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return config{}, err
+	}
+	return cfg, nil
+}
+
+// redacted returns the config with any credentials removed, so it can be logged
+func (c config) redacted() config {
+	if proxyURL, err := url.Parse(c.ClientProxy); err == nil && proxyURL.User != nil {
+		proxyURL.User = url.User("redacted")
+		c.ClientProxy = proxyURL.String()
+	}
+	if c.AdminToken != "" {
+		c.AdminToken = "redacted"
+	}
+	return c
+}
+
+func (c config) logLevel() (logrus.Level, error) {
+	return logrus.ParseLevel(c.LogLevel)
+}
+
+func (c config) cacheControlHeader() (string, error) {
+	duration, err := time.ParseDuration(c.CacheDuration)
+	if err != nil {
+		return "", fmt.Errorf("cache-duration: %w", err)
+	}
+	return fmt.Sprintf("max-age=%s, public", strconv.FormatFloat(duration.Seconds(), 'f', 0, 64)), nil
+}
+
+func (c config) rateLimits() (organisations.RateLimit, map[string]organisations.RateLimit, error) {
+	defaultLimit, err := organisations.ParseRateLimit(c.RateLimit)
+	if err != nil {
+		return organisations.RateLimit{}, nil, err
+	}
+	clientLimits, err := organisations.ParseClientRateLimits(c.ClientRateLimits)
+	if err != nil {
+		return organisations.RateLimit{}, nil, err
+	}
+	return defaultLimit, clientLimits, nil
+}
+
+// rateLimitConsumerHeaders returns the headers naming the consumers of client-rate-limits. Callers able to
+// authenticate are limited by who they are instead, so that anonymous callers cannot claim their limits in a header.
+func (c config) rateLimitConsumerHeaders() []string {
+	if c.AuthJWKSFile != "" || c.AuthAPIKeysFile != "" {
+		return nil
+	}
+	return c.RateLimitConsumerHeaders
+}
+
+// organisationSource returns the source organisations are read from, or nil for public-concepts-api
+func (c config) organisationSource() (organisations.OrganisationSource, error) {
+	switch c.OrganisationSource {
+	case "", "concepts-api":
+		return nil, nil
+	case "memory":
+		if c.OrganisationSourceFile == "" {
+			return nil, fmt.Errorf("organisation-source-file is required by the memory organisation source")
+		}
+		return organisations.LoadMemoryOrganisationSource(c.OrganisationSourceFile)
+	default:
+		return nil, fmt.Errorf("organisation-source '%s' is not one of concepts-api or memory", c.OrganisationSource)
+	}
+}
+
+// contractValidators return the validators of version 1 and version 2 organisations against the API definition,
+// or nil when contract-validation is off
+func (c config) contractValidators() (v1 *organisations.ContractValidator, v2 *organisations.ContractValidator, err error) {
+	mode, err := organisations.ParseContractMode(c.ContractValidation)
+	if err != nil || mode == "" {
+		return nil, nil, err
+	}
+	if v1, err = organisations.NewContractValidator(apiDefinition, "Organisation", mode); err != nil {
+		return nil, nil, err
+	}
+	if v2, err = organisations.NewContractValidator(apiDefinition, "OrganisationV2", mode); err != nil {
+		return nil, nil, err
+	}
+	return v1, v2, nil
+}
+
+// upstreamClient wraps the client calling public-concepts-api to record its responses in record-dir, or replaces
+// it to serve the responses recorded in replay-dir
+func (c config) upstreamClient(client organisations.HTTPClient, ftLogger *logger.UPPLogger) (organisations.HTTPClient, error) {
+	switch {
+	case c.RecordDir != "" && c.ReplayDir != "":
+		return nil, fmt.Errorf("record-dir and replay-dir cannot both be set")
+	case c.RecordDir != "":
+		ftLogger.WithField("dir", c.RecordDir).Info("Recording public-concepts-api responses")
+		return organisations.NewRecordingClient(client, c.RecordDir, ftLogger)
+	case c.ReplayDir != "":
+		ftLogger.WithField("dir", c.ReplayDir).Info("Replaying recorded public-concepts-api responses")
+		return organisations.NewReplayClient(c.ReplayDir, ftLogger)
+	default:
+		return client, nil
+	}
+}
+
+// authenticator returns the authenticator of callers, or nil when no JWKS, API keys or field scopes are configured
+func (c config) authenticator(ftLogger *logger.UPPLogger, registerer prometheus.Registerer) (*organisations.Authenticator, error) {
+	fieldScopes, err := organisations.ParseFieldScopes(c.AuthFieldScopes)
+	if err != nil {
+		return nil, fmt.Errorf("auth-field-scopes: %w", err)
+	}
+	if c.AuthJWKSFile == "" && c.AuthAPIKeysFile == "" {
+		if c.AuthRequired {
+			return nil, fmt.Errorf("auth-required needs auth-jwks-file or auth-api-keys-file")
+		}
+		if c.WebhookScope != "" {
+			return nil, fmt.Errorf("webhook-scope needs auth-jwks-file or auth-api-keys-file")
+		}
+		if len(fieldScopes) == 0 {
+			return nil, nil
+		}
+	}
+	authCfg := organisations.AuthConfig{
+		Issuer:      c.AuthJWTIssuer,
+		Audience:    c.AuthJWTAudience,
+		FieldScopes: fieldScopes,
+		Required:    c.AuthRequired,
+		PublicPaths: c.AuthPublicPaths,
+	}
+	if c.AuthJWKSFile != "" {
+		if authCfg.Keys, err = organisations.LoadJSONWebKeySet(c.AuthJWKSFile); err != nil {
+			return nil, err
+		}
+	}
+	if c.AuthAPIKeysFile != "" {
+		if authCfg.APIKeys, err = organisations.LoadAPIKeys(c.AuthAPIKeysFile); err != nil {
+			return nil, err
+		}
+	}
+	return organisations.NewAuthenticator(authCfg, ftLogger, registerer), nil
+}
+
+// organisationCache returns the cache of organisations, or nil when org-cache-ttl disables it
+func (c config) organisationCache() (*organisations.OrganisationCache, error) {
+	ttl, err := time.ParseDuration(c.OrgCacheTTL)
+	if err != nil {
+		return nil, fmt.Errorf("org-cache-ttl: %w", err)
+	}
+	if ttl <= 0 {
+		return nil, nil
+	}
+	return organisations.NewOrganisationCache(ttl, c.OrgCacheSize), nil
+}
+
+func (c config) serverConfig() (serverConfig, error) {
+	d := durations{}
+	cfg := serverConfig{
+		port:                  c.Port,
+		readTimeout:           d.parse("read-timeout", c.ReadTimeout),
+		readHeaderTimeout:     d.parse("read-header-timeout", c.ReadHeaderTimeout),
+		writeTimeout:          d.parse("write-timeout", c.WriteTimeout),
+		idleTimeout:           d.parse("idle-timeout", c.IdleTimeout),
+		preStopDelay:          d.parse("pre-stop-delay", c.PreStopDelay),
+		shutdownTimeout:       d.parse("shutdown-timeout", c.ShutdownTimeout),
+		healthInterval:        d.parse("health-interval", c.HealthInterval),
+		healthTimeout:         d.parse("health-timeout", c.HealthTimeout),
+		healthDegradedLatency: d.parse("health-degraded-latency", c.HealthDegradedLatency),
+		canaryUUID:            c.CanaryUUID,
+		adminToken:            c.AdminToken,
+		conceptEventsBrokers:  c.ConceptEventsBrokers,
+		conceptEventsTopic:    c.ConceptEventsTopic,
+		conceptEventsRefresh:  c.ConceptEventsRefresh,
+		webhookScope:          c.WebhookScope,
+		webhookDB:             c.WebhookDB,
+		webhookTimeout:        d.parse("webhook-timeout", c.WebhookTimeout),
+		webhooks: organisations.WebhookConfig{
+			PollInterval:   d.parse("webhook-poll-interval", c.WebhookPollInterval),
+			MaxAttempts:    c.WebhookMaxAttempts,
+			InitialBackoff: d.parse("webhook-initial-backoff", c.WebhookInitialBackoff),
+			MaxBackoff:     d.parse("webhook-max-backoff", c.WebhookMaxBackoff),
+			CallbackHosts:  c.WebhookCallbackHosts,
+		},
+	}
+	return cfg, d.err
+}
+
+func (c config) clientConfig() (clientConfig, error) {
+	d := durations{}
+	cfg := clientConfig{
+		timeout:               d.parse("client-timeout", c.ClientTimeout),
+		dialTimeout:           d.parse("client-dial-timeout", c.ClientDialTimeout),
+		tlsHandshakeTimeout:   d.parse("client-tls-handshake-timeout", c.ClientTLSHandshakeTimeout),
+		responseHeaderTimeout: d.parse("client-response-header-timeout", c.ClientResponseHeaderTimeout),
+		idleConnTimeout:       d.parse("client-idle-conn-timeout", c.ClientIdleConnTimeout),
+		keepAlive:             d.parse("client-keep-alive", c.ClientKeepAlive),
+		reuseConnections:      c.ClientReuseConnections,
+		maxIdleConnsPerHost:   c.ClientMaxIdleConnsPerHost,
+		maxConnsPerHost:       c.ClientMaxConnsPerHost,
+		http2:                 c.ClientHTTP2,
+		proxy:                 c.ClientProxy,
+	}
+	return cfg, d.err
+}
+
+// durations parses a series of options, keeping the first error
+type durations struct {
+	err error
+}
+
+func (d *durations) parse(name string, value string) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil && d.err == nil {
+		d.err = fmt.Errorf("%s: %w", name, err)
+	}
+	return duration
+}
+
+// changedOptions lists the names of the options that differ between two configs
+func changedOptions(previous config, current config) []string {
+	changed := []string{}
+	p, c := reflect.ValueOf(previous), reflect.ValueOf(current)
+	for i := 0; i < p.NumField(); i++ {
+		if !reflect.DeepEqual(p.Field(i).Interface(), c.Field(i).Interface()) {
+			changed = append(changed, strings.Split(p.Type().Field(i).Tag.Get("json"), ",")[0])
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// reloader applies the reloadable options of the config file to the running service when the file changes
+// or the service receives SIGHUP
+type reloader struct {
+	path    string
+	base    config
+	current config
+	data    []byte
+	logger  *logger.UPPLogger
+	client  *reloadableClient
+	metrics *clientMetrics
+	limiter *organisations.RateLimiter
+}
+
+// reload reads the config file and applies it. Nothing is applied unless every reloadable option is valid.
+func (r *reloader) reload(force bool) error {
+	cfg, data, err := loadConfig(r.base, r.path)
+	if err != nil {
+		return err
+	}
+	if !force && bytes.Equal(data, r.data) {
+		return nil
+	}
+	r.data = data
+
+	changed := changedOptions(r.current, cfg)
+	reloaded, ignored := []string{}, []string{}
+	for _, option := range changed {
+		if reloadableOptions[option] {
+			reloaded = append(reloaded, option)
+		} else {
+			ignored = append(ignored, option)
+		}
+	}
+	if len(ignored) > 0 {
+		r.logger.WithField("options", ignored).Warn("[Config] Changed options only take effect on restart")
+	}
+	if len(reloaded) == 0 {
+		return nil
+	}
+
+	level, err := cfg.logLevel()
+	if err != nil {
+		return err
+	}
+	cacheControl, err := cfg.cacheControlHeader()
+	if err != nil {
+		return err
+	}
+	defaultLimit, clientLimits, err := cfg.rateLimits()
+	if err != nil {
+		return err
+	}
+	clientCfg, err := cfg.clientConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newHTTPClient(clientCfg, r.metrics)
+	if err != nil {
+		return err
+	}
+
+	r.logger.SetLevel(level)
+	organisations.SetCacheControlHeader(cacheControl)
+	r.limiter.SetLimits(defaultLimit, clientLimits)
+	r.client.set(client)
+
+	// Restart-only options keep their running values, so they are reported again on the next change
+	for _, option := range ignored {
+		copyOption(&cfg, r.current, option)
+	}
+	r.current = cfg
+	r.logger.WithField("options", reloaded).WithField("config", cfg.redacted()).Info("[Config] Reloaded configuration")
+	return nil
+}
+
+func copyOption(dst *config, src config, option string) {
+	d, s := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src)
+	for i := 0; i < d.NumField(); i++ {
+		if strings.Split(d.Type().Field(i).Tag.Get("json"), ",")[0] == option {
+			d.Field(i).Set(s.Field(i))
+		}
+	}
+}
+
+// watch reloads the config file on SIGHUP, and whenever the directory holding it changes, until ctx is done.
+// Watching the directory rather than the file follows editors and Kubernetes ConfigMaps replacing the file.
+func (r *reloader) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err = watcher.Add(filepath.Dir(r.path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	// Writing a file raises several events, the first often while it is still empty, so reload once they settle
+	settled := time.NewTimer(0)
+	<-settled.C
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(hangup)
+		defer settled.Stop()
+		for {
+			var err error
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangup:
+				err = r.reload(true)
+			case <-watcher.Events:
+				settled.Reset(configSettleDelay)
+			case <-settled.C:
+				err = r.reload(false)
+			case err = <-watcher.Errors:
+			}
+			if err != nil {
+				r.logger.WithError(err).Error("[Config] Failed to reload configuration, keeping the current one")
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metrics "github.com/rcrowley/go-metrics"
+)
+
+var timerQuantiles = []float64{0.5, 0.95, 0.99}
+
+// goMetricsCollector exposes the timers of a go-metrics registry, such as the per method timers recorded by
+// httphandlers.HTTPMetricsHandler, as Prometheus summaries
+type goMetricsCollector struct {
+	registry metrics.Registry
+	desc     *prometheus.Desc
+}
+
+func newGoMetricsCollector(registry metrics.Registry) goMetricsCollector {
+	return goMetricsCollector{
+		registry: registry,
+		desc: prometheus.NewDesc(
+			"public_organisations_api_http_request_duration_seconds",
+			"Duration of HTTP requests by method, as recorded by the HTTP metrics handler.",
+			[]string{"method"}, nil,
+		),
+	}
+}
+
+func (c goMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c goMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.registry.Each(func(name string, i interface{}) {
+		timer, ok := i.(metrics.Timer)
+		if !ok {
+			return
+		}
+		snapshot := timer.Snapshot()
+		percentiles := snapshot.Percentiles(timerQuantiles)
+		quantiles := make(map[float64]float64, len(timerQuantiles))
+		for i, q := range timerQuantiles {
+			quantiles[q] = percentiles[i] / float64(time.Second)
+		}
+		ch <- prometheus.MustNewConstSummary(c.desc, uint64(snapshot.Count()), float64(snapshot.Sum())/float64(time.Second), quantiles, name)
+	})
+}
@@ -0,0 +1,120 @@
+package organisations
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"regexp"
+	"strings"
+
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/gorilla/mux"
+)
+
+var (
+	wholeUUID  = regexp.MustCompile("^" + validUUID)
+	uuidPrefix = regexp.MustCompile(`^[0-9a-fA-F-]+$`)
+)
+
+// PurgeResult describes what a purge removed, with the surrogate keys the edge should purge to match
+type PurgeResult struct {
+	Purged        int      `json:"purged"`
+	SurrogateKeys []string `json:"surrogateKeys"`
+}
+
+// RegisterAdminHandlers exposes the organisation cache under /__admin to callers presenting token as a bearer token.
+// There is nothing to administer without a cache, so it registers nothing then.
+func (h *OrganisationsHandler) RegisterAdminHandlers(router *mux.Router, token string) {
+	if h.cache == nil {
+		return
+	}
+	h.logger.Info("Registering admin handlers")
+	authorised := func(handler http.HandlerFunc) http.Handler {
+		return requireBearerToken(token, handler)
+	}
+
+	router.Handle("/__admin/cache", methodHandler{
+		"GET":    authorised(h.GetCacheStats),
+		"DELETE": authorised(h.PurgeCache),
+	})
+	router.Handle("/__admin/cache/{uuid}", methodHandler{
+		"GET":    authorised(h.GetCacheEntry),
+		"DELETE": authorised(h.PurgeCacheEntry),
+	})
+}
+
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeProblem(w, r, http.StatusUnauthorized, errorUnauthorised, "admin token is missing or invalid")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetCacheStats returns the size of the organisation cache and the counts of its use
+func (h *OrganisationsHandler) GetCacheStats(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, r, http.StatusOK, h.cache.Stats())
+}
+
+// GetCacheEntry returns the organisation cached for a UUID
+func (h *OrganisationsHandler) GetCacheEntry(w http.ResponseWriter, r *http.Request) {
+	uuid := mux.Vars(r)["uuid"]
+	entry, ok := h.cache.Peek(uuid)
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, errorNotFound, "organisation is not cached")
+		return
+	}
+	writeAdminJSON(w, r, http.StatusOK, entry)
+}
+
+// PurgeCacheEntry removes the organisation cached for a UUID, including where it was requested by an alternate UUID
+func (h *OrganisationsHandler) PurgeCacheEntry(w http.ResponseWriter, r *http.Request) {
+	uuid := mux.Vars(r)["uuid"]
+	if !wholeUUID.MatchString(uuid) {
+		writeProblem(w, r, http.StatusBadRequest, errorInvalidUUID, "uuid is invalid")
+		return
+	}
+	purged, keys := h.cache.Purge(uuid)
+	h.purged(w, r, "uuid", uuid, purged, keys)
+}
+
+// PurgeCache removes the organisations whose UUID starts with the prefix parameter, or every organisation when all is true
+func (h *OrganisationsHandler) PurgeCache(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	switch {
+	case query.Get("all") == "true":
+		purged, keys := h.cache.Flush()
+		h.purged(w, r, "all", "", purged, keys)
+	case query.Get("prefix") != "":
+		prefix := query.Get("prefix")
+		if !uuidPrefix.MatchString(prefix) {
+			writeProblem(w, r, http.StatusBadRequest, errorInvalidParameters, "prefix must be the start of a uuid")
+			return
+		}
+		purged, keys := h.cache.PurgePrefix(prefix)
+		h.purged(w, r, "prefix", prefix, purged, keys)
+	default:
+		writeProblem(w, r, http.StatusBadRequest, errorInvalidParameters, "either prefix or all=true is required")
+	}
+}
+
+// purged logs the surrogate keys for the edge to purge and returns them to the caller
+func (h *OrganisationsHandler) purged(w http.ResponseWriter, r *http.Request, scope string, value string, purged int, keys []string) {
+	h.logger.WithTransactionID(transactionidutils.GetTransactionIDFromRequest(r)).
+		WithField("scope", scope).
+		WithField("value", value).
+		WithField("purged", purged).
+		WithField("surrogateKeys", strings.Join(keys, " ")).
+		Info("purged organisation cache")
+	w.Header().Set("Surrogate-Key", strings.Join(keys, " "))
+	writeAdminJSON(w, r, http.StatusOK, PurgeResult{Purged: purged, SurrogateKeys: keys})
+}
+
+func writeAdminJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	if err := writeEncoded(w, JSONFormat, status, v); err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "response could not be encoded")
+	}
+}
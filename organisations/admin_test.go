@@ -0,0 +1,93 @@
+package organisations
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAdminRouter(cache *OrganisationCache) *mux.Router {
+	bh := NewHandler(&mockHTTPClient{}, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"), WithCache(cache))
+	router := mux.NewRouter()
+	bh.RegisterAdminHandlers(router, "secret")
+	return router
+}
+
+func adminRequest(router *mux.Router, method string, url string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	router := newTestAdminRouter(NewOrganisationCache(time.Minute, 0))
+
+	assert.Equal(t, http.StatusUnauthorized, adminRequest(router, "GET", "/__admin/cache", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, adminRequest(router, "DELETE", "/__admin/cache?all=true", "wrong").Code)
+	assert.Equal(t, http.StatusOK, adminRequest(router, "GET", "/__admin/cache", "secret").Code)
+}
+
+func TestAdminIsNotRegisteredWithoutCache(t *testing.T) {
+	bh := NewHandler(&mockHTTPClient{}, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"))
+	router := mux.NewRouter()
+	bh.RegisterAdminHandlers(router, "secret")
+
+	assert.Equal(t, http.StatusNotFound, adminRequest(router, "GET", "/__admin/cache", "secret").Code)
+}
+
+func TestAdminCacheEntries(t *testing.T) {
+	cache := NewOrganisationCache(time.Minute, 0)
+	cache.Set("7c5218a0-3755-463e-abbc-1a1632cfd1da", testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da"))
+	cache.Set("7c52aaaa-3755-463e-abbc-1a1632cfd1da", testCacheOrganisation("7c52aaaa-3755-463e-abbc-1a1632cfd1da"))
+	cache.Set("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", testCacheOrganisation("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6"))
+	router := newTestAdminRouter(cache)
+
+	rec := adminRequest(router, "GET", "/__admin/cache/7c5218a0-3755-463e-abbc-1a1632cfd1da", "secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var entry CacheEntry
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&entry))
+	assert.Equal(t, "Nintendo Co Ltd", entry.Organisation.PrefLabel)
+
+	rec = adminRequest(router, "DELETE", "/__admin/cache/7c5218a0-3755-463e-abbc-1a1632cfd1da", "secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result PurgeResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+	assert.Equal(t, PurgeResult{Purged: 1, SurrogateKeys: []string{"organisation-7c5218a0-3755-463e-abbc-1a1632cfd1da"}}, result)
+	assert.Equal(t, "organisation-7c5218a0-3755-463e-abbc-1a1632cfd1da", rec.Header().Get("Surrogate-Key"))
+	assert.Equal(t, http.StatusNotFound, adminRequest(router, "GET", "/__admin/cache/7c5218a0-3755-463e-abbc-1a1632cfd1da", "secret").Code)
+
+	rec = adminRequest(router, "DELETE", "/__admin/cache?prefix=7C52", "secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+	assert.Equal(t, 1, result.Purged)
+
+	rec = adminRequest(router, "DELETE", "/__admin/cache?all=true", "secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+	assert.Equal(t, PurgeResult{Purged: 1, SurrogateKeys: []string{"organisations"}}, result)
+
+	var stats CacheStats
+	rec = adminRequest(router, "GET", "/__admin/cache", "secret")
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	assert.Equal(t, 0, stats.Entries)
+	assert.Equal(t, uint64(3), stats.Purged)
+}
+
+func TestAdminRejectsInvalidPurges(t *testing.T) {
+	router := newTestAdminRouter(NewOrganisationCache(time.Minute, 0))
+
+	assert.Equal(t, http.StatusBadRequest, adminRequest(router, "DELETE", "/__admin/cache", "secret").Code)
+	assert.Equal(t, http.StatusBadRequest, adminRequest(router, "DELETE", "/__admin/cache?prefix=nintendo", "secret").Code)
+	assert.Equal(t, http.StatusBadRequest, adminRequest(router, "DELETE", "/__admin/cache/not-a-uuid", "secret").Code)
+}
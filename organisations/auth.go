@@ -0,0 +1,363 @@
+package organisations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	apiKeyHeader = "X-Api-Key"
+	authJWT      = "jwt"
+	authAPIKey   = "api-key"
+)
+
+// fieldAuthorities maps fields of organisations to the authority of the identifiers with the same value, which are
+// redacted along with the field
+var fieldAuthorities = map[string]string{
+	"leiCode": leiAuthority,
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Subject is the sub claim of a JWT or the name of an API key
+	Subject string
+	// Method is jwt or api-key
+	Method string
+	Scopes []string
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext returns the caller authenticated by the Authenticator middleware, ok is false for
+// anonymous callers
+func PrincipalFromContext(ctx context.Context) (principal Principal, ok bool) {
+	principal, ok = ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// APIKey is a static key a consumer can authenticate with in the X-Api-Key header, granting its scopes
+type APIKey struct {
+	Name   string   `json:"name" yaml:"name"`
+	Key    string   `json:"key" yaml:"key"`
+	Scopes []string `json:"scopes" yaml:"scopes"`
+}
+
+// LoadAPIKeys reads a YAML or JSON list of API keys. Files ending in .json are read as JSON, anything else as YAML.
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading API keys file: %w", err)
+	}
+	var keys []APIKey
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &keys)
+	} else {
+		err = yaml.Unmarshal(data, &keys)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing API keys file %s: %w", path, err)
+	}
+	for i, key := range keys {
+		if key.Name == "" || key.Key == "" {
+			return nil, fmt.Errorf("API key %d needs a name and a key", i)
+		}
+	}
+	return keys, nil
+}
+
+// ParseFieldScopes parses scope=field field entries into the fields each scope entitles its callers to see
+func ParseFieldScopes(entries []string) (map[string][]string, error) {
+	fieldScopes := map[string][]string{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		scope, fields, found := strings.Cut(entry, "=")
+		scope = strings.TrimSpace(scope)
+		if !found || scope == "" || len(strings.Fields(fields)) == 0 {
+			return nil, fmt.Errorf("field scope '%s' is not of the form scope=field field", entry)
+		}
+		fieldScopes[scope] = append(fieldScopes[scope], strings.Fields(fields)...)
+	}
+	return fieldScopes, nil
+}
+
+// AuthConfig configures how callers are authenticated and which fields they may see
+type AuthConfig struct {
+	// Keys verify the JWTs presented as bearer tokens, nil rejects any JWT
+	Keys *JSONWebKeySet
+	// Issuer and Audience are the iss and aud claims JWTs must have, when not empty
+	Issuer   string
+	Audience string
+	APIKeys  []APIKey
+	// FieldScopes maps scopes to the fields they entitle callers to see. Fields mapped to any scope are redacted for
+	// callers without one of their scopes, other fields are seen by every caller.
+	FieldScopes map[string][]string
+	// Required rejects anonymous requests to routes outside of PublicPaths
+	Required bool
+	// PublicPaths are prefixes of the paths served without authentication
+	PublicPaths []string
+}
+
+// Authenticator authenticates callers with JWTs or API keys and redacts the fields they are not entitled to
+type Authenticator struct {
+	keys        *JSONWebKeySet
+	issuer      string
+	audience    string
+	apiKeys     map[[sha256.Size]byte]APIKey
+	fieldScopes map[string][]string
+	restricted  map[string]bool
+	required    bool
+	publicPaths []string
+	logger      *logger.UPPLogger
+	now         func() time.Time
+	failures    *prometheus.CounterVec
+}
+
+// NewAuthenticator creates an Authenticator and registers its metrics with registerer
+func NewAuthenticator(cfg AuthConfig, ftLogger *logger.UPPLogger, registerer prometheus.Registerer) *Authenticator {
+	a := &Authenticator{
+		keys:        cfg.Keys,
+		issuer:      cfg.Issuer,
+		audience:    cfg.Audience,
+		apiKeys:     map[[sha256.Size]byte]APIKey{},
+		fieldScopes: cfg.FieldScopes,
+		restricted:  map[string]bool{},
+		required:    cfg.Required,
+		publicPaths: cfg.PublicPaths,
+		logger:      ftLogger,
+		now:         time.Now,
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "authentication_failures_total",
+			Help:      "Requests rejected because their credentials were invalid or missing, by authentication method, none when credentials were required but missing.",
+		}, []string{"method"}),
+	}
+	registerer.MustRegister(a.failures)
+	for _, key := range cfg.APIKeys {
+		a.apiKeys[sha256.Sum256([]byte(key.Key))] = key
+	}
+	for _, fields := range cfg.FieldScopes {
+		for _, field := range fields {
+			a.restricted[field] = true
+		}
+	}
+	return a
+}
+
+// WithAuthenticator redacts the fields of organisations the caller authenticated by the Authenticator is not
+// entitled to
+func WithAuthenticator(a *Authenticator) HandlerOption {
+	return func(h *OrganisationsHandler) {
+		h.auth = a
+	}
+}
+
+// Middleware authenticates the caller of requests to paths outside of the public paths. Requests with invalid
+// credentials are rejected with 401 Unauthorized, and so are anonymous requests when authentication is required.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		principal, authenticated, err := a.authenticate(r)
+		if err != nil {
+			a.failures.WithLabelValues(principal.Method).Inc()
+			a.logger.WithTransactionID(transactionidutils.GetTransactionIDFromRequest(r)).
+				WithError(err).
+				WithField("method", principal.Method).
+				Warn("rejected request with invalid credentials")
+			unauthorised(w, r, "credentials are invalid")
+			return
+		}
+		if !authenticated {
+			if a.required {
+				a.failures.WithLabelValues("none").Inc()
+				unauthorised(w, r, "credentials are required")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
+
+func (a *Authenticator) isPublic(path string) bool {
+	for _, prefix := range a.publicPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate reads the caller of r from its API key or bearer token. authenticated is false for requests without
+// either, and the method of the principal names the credentials that were presented when err is not nil.
+func (a *Authenticator) authenticate(r *http.Request) (principal Principal, authenticated bool, err error) {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		principal.Method = authAPIKey
+		apiKey, ok := a.apiKeys[sha256.Sum256([]byte(key))]
+		if !ok {
+			return principal, false, errors.New("API key is not known")
+		}
+		principal.Subject, principal.Scopes = apiKey.Name, apiKey.Scopes
+		return principal, true, nil
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return principal, false, nil
+	}
+	principal.Method = authJWT
+	scheme, token, _ := strings.Cut(authorization, " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return principal, false, errors.New("authorization is not a bearer token")
+	}
+	if a.keys == nil {
+		return principal, false, errors.New("JWTs are not accepted without a JWKS")
+	}
+	claims, err := verifyJWT(strings.TrimSpace(token), a.keys, a.issuer, a.audience, a.now())
+	if err != nil {
+		return principal, false, err
+	}
+	principal.Subject, principal.Scopes = claims.Subject, claims.scopes()
+	return principal, true, nil
+}
+
+func unauthorised(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="organisations"`)
+	writeProblem(w, r, http.StatusUnauthorized, errorUnauthorised, detail)
+}
+
+// requireScope serves the callers authenticated with scope. Anonymous callers are rejected with 401 Unauthorized,
+// and the callers without the scope with 403 Forbidden.
+func requireScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := PrincipalFromContext(r.Context())
+		if !ok {
+			unauthorised(w, r, "credentials are required")
+			return
+		}
+		for _, s := range principal.Scopes {
+			if s == scope {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		writeProblem(w, r, http.StatusForbidden, errorForbidden, "scope "+scope+" is required")
+	})
+}
+
+// hiddenFields are the restricted fields a caller with the scopes has none of the scopes of
+func (a *Authenticator) hiddenFields(scopes []string) map[string]bool {
+	if len(a.restricted) == 0 {
+		return nil
+	}
+	hidden := make(map[string]bool, len(a.restricted))
+	for field := range a.restricted {
+		hidden[field] = true
+	}
+	for _, scope := range scopes {
+		for _, field := range a.fieldScopes[scope] {
+			delete(hidden, field)
+		}
+	}
+	return hidden
+}
+
+// callerSubject is the subject of the caller of ctx, empty for anonymous callers
+func callerSubject(ctx context.Context) string {
+	principal, _ := PrincipalFromContext(ctx)
+	return principal.Subject
+}
+
+// callerScopes are the scopes of the caller of ctx, none for anonymous callers
+func callerScopes(ctx context.Context) []string {
+	principal, _ := PrincipalFromContext(ctx)
+	return principal.Scopes
+}
+
+// redact empties the top level fields of the organisations the caller of r is not entitled to, named by their JSON
+// names, so that the optional ones are left out of the response
+func (h *OrganisationsHandler) redact(w http.ResponseWriter, r *http.Request, organisations ...interface{}) {
+	hidden := h.hiddenFields(w, r)
+	if len(hidden) == 0 {
+		return
+	}
+	for _, org := range organisations {
+		redactFields(reflect.ValueOf(org).Elem(), hidden)
+	}
+}
+
+// hiddenAuthorities are the authorities of the identifiers the caller of r is not entitled to
+func (h *OrganisationsHandler) hiddenAuthorities(w http.ResponseWriter, r *http.Request) map[string]bool {
+	hidden := map[string]bool{}
+	for field := range h.hiddenFields(w, r) {
+		if authority, ok := fieldAuthorities[field]; ok {
+			hidden[authority] = true
+		}
+	}
+	return hidden
+}
+
+// hiddenFields are the fields the caller of r is not entitled to. Responses then differ by credentials, and say so
+// in the Vary header.
+func (h *OrganisationsHandler) hiddenFields(w http.ResponseWriter, r *http.Request) map[string]bool {
+	if h.auth == nil || len(h.auth.restricted) == 0 {
+		return nil
+	}
+	w.Header().Add("Vary", "Authorization, "+apiKeyHeader)
+	return h.auth.hiddenFields(callerScopes(r.Context()))
+}
+
+// redactWebhook empties the fields of the organisation of a webhook that a subscriber with the scopes is not
+// entitled to, and drops the changes to them. It reports whether any change is left to send.
+func (a *Authenticator) redactWebhook(payload *WebhookPayload, scopes []string) bool {
+	if a == nil {
+		return true
+	}
+	hidden := a.hiddenFields(scopes)
+	if len(hidden) == 0 {
+		return true
+	}
+	redactFields(reflect.ValueOf(&payload.Organisation).Elem(), hidden)
+	changes := []FieldChange{}
+	for _, change := range payload.Changes {
+		if !hidden[change.Field] {
+			changes = append(changes, change)
+		}
+	}
+	payload.Changes = changes
+	return len(changes) > 0
+}
+
+func redactFields(v reflect.Value, hidden map[string]bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct:
+			redactFields(v.Field(i), hidden)
+		case hidden[name] && field.IsExported():
+			v.Field(i).Set(reflect.Zero(field.Type))
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package organisations
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAPIKeysAndFieldScopes(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "keys.yml")
+	require.NoError(t, os.WriteFile(file, []byte("- name: next-app\n  key: s3cr3t\n  scopes: [organisations:lei]\n"), 0600))
+	keys, err := LoadAPIKeys(file)
+	require.NoError(t, err)
+	assert.Equal(t, []APIKey{{Name: "next-app", Key: "s3cr3t", Scopes: []string{"organisations:lei"}}}, keys)
+
+	require.NoError(t, os.WriteFile(file, []byte("- name: next-app\n"), 0600))
+	_, err = LoadAPIKeys(file)
+	assert.Error(t, err)
+
+	fieldScopes, err := ParseFieldScopes([]string{"organisations:lei=leiCode", "licensed=leiCode  postalCode", ""})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"organisations:lei": {"leiCode"}, "licensed": {"leiCode", "postalCode"}}, fieldScopes)
+	_, err = ParseFieldScopes([]string{"leiCode"})
+	assert.Error(t, err)
+	_, err = ParseFieldScopes([]string{"licensed="})
+	assert.Error(t, err)
+}
+
+func TestAuthenticatorRedactsFields(t *testing.T) {
+	signer := newTestSigner(t)
+	keys, err := ParseJSONWebKeySet(signer.jwks)
+	require.NoError(t, err)
+	registry := prometheus.NewRegistry()
+	auth := NewAuthenticator(AuthConfig{
+		Keys:        keys,
+		APIKeys:     []APIKey{{Name: "licensed-app", Key: "s3cr3t", Scopes: []string{"organisations:lei"}}, {Name: "other-app", Key: "0th3r"}},
+		FieldScopes: map[string][]string{"organisations:lei": {"leiCode"}, "organisations:postal": {"postalCode"}},
+		Required:    true,
+		PublicPaths: []string{"/__"},
+	}, logger.NewUPPInfoLogger("tests"), registry)
+
+	source := newTestMemorySource(t, getCompleteOrganisationAsConcept)
+	h := NewHandler(nil, "", logger.NewUPPInfoLogger("tests"), WithSource(source), WithAuthenticator(auth))
+	router := mux.NewRouter()
+	router.HandleFunc("/__gtg", func(w http.ResponseWriter, r *http.Request) {})
+	h.RegisterHandlers(router)
+	router.Use(auth.Middleware)
+
+	token := signer.sign(t, map[string]string{"alg": "ES256", "kid": "ec"}, map[string]interface{}{
+		"sub": "reader", "scope": "organisations:postal", "exp": time.Now().Add(time.Hour).Unix()})
+	for _, test := range []struct {
+		name           string
+		url            string
+		header         string
+		value          string
+		expectedCode   int
+		expectedFields map[string]bool
+	}{
+		{"public path", "/__gtg", "", "", http.StatusOK, nil},
+		{"anonymous", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", "", "", http.StatusUnauthorized, nil},
+		{"unknown API key", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", apiKeyHeader, "nintendo", http.StatusUnauthorized, nil},
+		{"invalid token", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", "Authorization", "Bearer nintendo", http.StatusUnauthorized, nil},
+		{"entitled API key", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", apiKeyHeader, "s3cr3t", http.StatusOK,
+			map[string]bool{"leiCode": true, "postalCode": false, "prefLabel": true}},
+		{"API key without scopes", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", apiKeyHeader, "0th3r", http.StatusOK,
+			map[string]bool{"leiCode": false, "postalCode": false, "prefLabel": true}},
+		{"token", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", "Authorization", "Bearer " + token, http.StatusOK,
+			map[string]bool{"leiCode": false, "postalCode": true, "prefLabel": true}},
+		{"version 2", "/v2/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", apiKeyHeader, "0th3r", http.StatusOK,
+			map[string]bool{"leiCode": false, "postalCode": false, "prefLabel": true}},
+	} {
+		req := httptest.NewRequest("GET", test.url, nil)
+		if test.header != "" {
+			req.Header.Set(test.header, test.value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, test.expectedCode, rec.Code, test.name)
+		if test.expectedCode == http.StatusUnauthorized {
+			assert.Equal(t, `Bearer realm="organisations"`, rec.Header().Get("WWW-Authenticate"), test.name)
+		}
+		if test.expectedFields == nil {
+			continue
+		}
+		assert.Contains(t, rec.Header().Values("Vary"), "Authorization, X-Api-Key", test.name)
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &fields), test.name)
+		for field, present := range test.expectedFields {
+			_, ok := fields[field]
+			assert.Equal(t, present, ok, "%s: %s", test.name, field)
+		}
+	}
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(auth.failures.WithLabelValues("none")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(auth.failures.WithLabelValues(authAPIKey)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(auth.failures.WithLabelValues(authJWT)))
+}
+
+func TestAuthenticatorAllowsAnonymousCallersUnlessRequired(t *testing.T) {
+	auth := NewAuthenticator(AuthConfig{FieldScopes: map[string][]string{"organisations:lei": {"leiCode"}}},
+		logger.NewUPPInfoLogger("tests"), prometheus.NewRegistry())
+	history := newTestHistoryStore(t)
+	h := NewHandler(nil, "", logger.NewUPPInfoLogger("tests"), WithSource(newTestMemorySource(t, getCompleteOrganisationAsConcept)),
+		WithAuthenticator(auth), WithHistoryStore(history))
+	router := mux.NewRouter()
+	h.RegisterHandlers(router)
+	router.Use(auth.Middleware)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "leiCode")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da/history", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "leiCode", "the history is redacted too")
+
+	snapshots, err := history.Versions("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	require.NoError(t, err)
+	assert.Equal(t, "353800FEEXU6I9M0ZF27", snapshots[0].Organisation.LegalEntityIdentifier, "redaction does not change what is stored")
+
+	req := httptest.NewRequest("GET", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", nil)
+	req.Header.Set("Authorization", "Bearer nintendo")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "JWTs are rejected without a JWKS")
+}
+
+func TestAuthenticatorRedactsLEIIdentifiers(t *testing.T) {
+	withLEIIdentifier := strings.Replace(getOrganisationWithIdentifiersAsConcept, `"leiCode": "353800FEEXU6I9M0ZF27",`, "", 1)
+	withLEIIdentifier = strings.Replace(withLEIIdentifier, `"identifiers": [`,
+		`"identifiers": [{"authority": "http://api.ft.com/system/LEI", "identifierValue": "353800FEEXU6I9M0ZF27"},`, 1)
+	auth := NewAuthenticator(AuthConfig{
+		APIKeys:     []APIKey{{Name: "compliance", Key: "k1", Scopes: []string{"organisations:lei"}}},
+		FieldScopes: map[string][]string{"organisations:lei": {"leiCode"}},
+	}, logger.NewUPPInfoLogger("tests"), prometheus.NewRegistry())
+
+	for name, concept := range map[string]string{"leiCode": getOrganisationWithIdentifiersAsConcept, "LEI identifier": withLEIIdentifier} {
+		h := NewHandler(nil, "", logger.NewUPPInfoLogger("tests"), WithSource(newTestMemorySource(t, concept)), WithAuthenticator(auth))
+		router := mux.NewRouter()
+		h.RegisterHandlers(router)
+		router.Use(auth.Middleware)
+		serve := func(url string, apiKey string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", url, nil)
+			if apiKey != "" {
+				req.Header.Set(apiKeyHeader, apiKey)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			return rec
+		}
+
+		rec := serve("/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da/identifiers", "")
+		assert.Equal(t, http.StatusOK, rec.Code, name)
+		assert.NotContains(t, rec.Body.String(), "353800FEEXU6I9M0ZF27", name)
+		assert.Contains(t, rec.Body.String(), "000C7F-E", "%s: other identifiers are kept", name)
+		assert.Contains(t, rec.Header().Values("Vary"), "Authorization, X-Api-Key", name)
+		rec = serve("/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da/identifiers", "k1")
+		assert.Contains(t, rec.Body.String(), `{"authority":"http://api.ft.com/system/LEI","identifierValue":"353800FEEXU6I9M0ZF27"}`, name)
+
+		rec = serve("/organisations?authority=LEI&identifierValue=353800FEEXU6I9M0ZF27", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code, "%s: anonymous callers cannot look organisations up by LEI", name)
+		rec = serve("/organisations?authority=LEI&identifierValue=353800FEEXU6I9M0ZF27&identifierValue=549300X7F3KS3JZNB234", "")
+		assert.Equal(t, http.StatusOK, rec.Code, name)
+		assert.JSONEq(t, `{"concordances":[]}`, rec.Body.String(), name)
+		rec = serve("/organisations?authority=LEI&identifierValue=353800FEEXU6I9M0ZF27", "k1")
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code, name)
+		assert.Equal(t, "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", rec.Header().Get("Location"), name)
+		rec = serve("/organisations?authority=FACTSET&identifierValue=000C7F-E", "")
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code, "%s: other authorities can still be looked up", name)
+	}
+}
@@ -0,0 +1,291 @@
+package organisations
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	allOrganisationsSurrogateKey = "organisations"
+	surrogateKeyPrefix           = "organisation-"
+	cacheSweepPeriod             = time.Minute
+)
+
+// OrganisationCache keeps transformed organisations for a fixed time, so that repeated requests for the same
+// organisation are answered without calling public-concepts-api. Entries are keyed by the requested UUID, in lower
+// case, so that the same organisation requested in either case is cached once.
+type OrganisationCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]CacheEntry
+	lastSweep time.Time
+	stats     CacheStats
+}
+
+// CacheEntry is a cached organisation and when it was stored
+type CacheEntry struct {
+	UUID         string       `json:"uuid"`
+	StoredAt     time.Time    `json:"storedAt"`
+	ExpiresAt    time.Time    `json:"expiresAt"`
+	Organisation Organisation `json:"organisation"`
+}
+
+// CacheStats describes the use of the cache since the service started
+type CacheStats struct {
+	Entries    int     `json:"entries"`
+	MaxEntries int     `json:"maxEntries"`
+	TTLSeconds float64 `json:"ttlSeconds"`
+	Hits       uint64  `json:"hits"`
+	Misses     uint64  `json:"misses"`
+	Expired    uint64  `json:"expired"`
+	Evicted    uint64  `json:"evicted"`
+	Purged     uint64  `json:"purged"`
+}
+
+// NewOrganisationCache creates a cache keeping organisations for ttl, holding at most maxEntries.
+// When it is full an arbitrary entry makes way for the new one.
+func NewOrganisationCache(ttl time.Duration, maxEntries int) *OrganisationCache {
+	return &OrganisationCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    map[string]CacheEntry{},
+	}
+}
+
+// WithCache answers requests for organisations from cache when it can
+func WithCache(cache *OrganisationCache) HandlerOption {
+	return func(h *OrganisationsHandler) {
+		h.cache = cache
+	}
+}
+
+// Get returns the organisation cached for uuid unless it has expired
+func (c *OrganisationCache) Get(uuid string) (CacheEntry, bool) {
+	uuid = strings.ToLower(uuid)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[uuid]
+	if ok && !c.now().Before(entry.ExpiresAt) {
+		delete(c.entries, uuid)
+		c.stats.Expired++
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		return CacheEntry{}, false
+	}
+	c.stats.Hits++
+	return entry, true
+}
+
+// Peek returns the entry cached for uuid without counting a hit or a miss
+func (c *OrganisationCache) Peek(uuid string) (CacheEntry, bool) {
+	uuid = strings.ToLower(uuid)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[uuid]
+	if ok && !c.now().Before(entry.ExpiresAt) {
+		return CacheEntry{}, false
+	}
+	return entry, ok
+}
+
+// Set caches the organisation requested as uuid
+func (c *OrganisationCache) Set(uuid string, org Organisation) {
+	uuid = strings.ToLower(uuid)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.sweep(now)
+	if _, ok := c.entries[uuid]; !ok && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		for evicted := range c.entries {
+			delete(c.entries, evicted)
+			c.stats.Evicted++
+			break
+		}
+	}
+	c.entries[uuid] = CacheEntry{UUID: uuid, StoredAt: now, ExpiresAt: now.Add(c.ttl), Organisation: org}
+}
+
+// Purge removes the entries requested as uuid or whose canonical organisation is uuid. It returns how many
+// entries it removed and the surrogate keys the edge should purge.
+func (c *OrganisationCache) Purge(uuid string) (int, []string) {
+	uuid = strings.ToLower(uuid)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := map[string]bool{surrogateKey(uuid): true}
+	purged := 0
+	for key, entry := range c.entries {
+		if key == uuid || canonicalUUID(entry.Organisation.ID) == uuid {
+			c.remove(key, keys)
+			purged++
+		}
+	}
+	return purged, sortedKeys(keys)
+}
+
+// PurgePrefix removes the entries whose requested UUID starts with prefix. It returns how many entries it
+// removed and the surrogate keys the edge should purge.
+func (c *OrganisationCache) PurgePrefix(prefix string) (int, []string) {
+	prefix = strings.ToLower(prefix)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := map[string]bool{}
+	purged := 0
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(key, keys)
+			purged++
+		}
+	}
+	return purged, sortedKeys(keys)
+}
+
+// Flush removes every entry. It returns how many entries it removed and the surrogate key purging every
+// organisation from the edge.
+func (c *OrganisationCache) Flush() (int, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	purged := len(c.entries)
+	c.stats.Purged += uint64(purged)
+	c.entries = map[string]CacheEntry{}
+	return purged, []string{allOrganisationsSurrogateKey}
+}
+
+// Stats returns the current size of the cache and the counts of its use
+func (c *OrganisationCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(c.now())
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	stats.MaxEntries = c.maxEntries
+	stats.TTLSeconds = c.ttl.Seconds()
+	return stats
+}
+
+func (c *OrganisationCache) remove(key string, surrogateKeys map[string]bool) {
+	entry := c.entries[key]
+	delete(c.entries, key)
+	c.stats.Purged++
+	surrogateKeys[surrogateKey(key)] = true
+	if canonical := canonicalUUID(entry.Organisation.ID); canonical != "" {
+		surrogateKeys[surrogateKey(canonical)] = true
+	}
+}
+
+// sweep removes the expired entries, at most once a sweep period
+func (c *OrganisationCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < cacheSweepPeriod {
+		return
+	}
+	c.lastSweep = now
+	for key, entry := range c.entries {
+		if !now.Before(entry.ExpiresAt) {
+			delete(c.entries, key)
+			c.stats.Expired++
+		}
+	}
+}
+
+// getOrganisation answers from the cache if it can, and caches the organisations it reads otherwise
+func (h *OrganisationsHandler) getOrganisation(ctx context.Context, uuid string, transID string) (org Organisation, outcome lookupOutcome, cached bool, err error) {
+	if h.cache == nil {
+		org, outcome, err = h.getOrganisationFromSource(ctx, uuid, transID)
+		return org, outcome, false, err
+	}
+	if entry, ok := h.cache.Get(uuid); ok {
+		return entry.Organisation, outcomeFound, true, nil
+	}
+	org, outcome, err = h.getOrganisationFromSource(ctx, uuid, transID)
+	if err == nil && outcome == outcomeFound {
+		h.cache.Set(uuid, org)
+	}
+	return org, outcome, false, err
+}
+
+// Embedding returns the keys of the entries for any of the organisations with the UUIDs, or embedding any of them
+func (c *OrganisationCache) Embedding(uuids []string) []string {
+	return c.keysWhere(func(key string, org Organisation) bool {
+		for _, uuid := range uuids {
+			if key == uuid || canonicalUUID(org.ID) == uuid {
+				return true
+			}
+			for _, embedded := range embeddedUUIDs(org) {
+				if embedded == uuid {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// KeysFor returns the keys of the entries for any of the organisations with the UUIDs
+func (c *OrganisationCache) KeysFor(uuids []string) []string {
+	return c.keysWhere(func(key string, org Organisation) bool {
+		for _, uuid := range uuids {
+			if key == uuid || canonicalUUID(org.ID) == uuid {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (c *OrganisationCache) keysWhere(matches func(key string, org Organisation) bool) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := map[string]bool{}
+	for key, entry := range c.entries {
+		if matches(key, entry.Organisation) {
+			keys[key] = true
+		}
+	}
+	return sortedKeys(keys)
+}
+
+// surrogateKeys are the keys the edge tags a response for the organisation with. Besides the organisation itself
+// they include the organisations and instruments it embeds, so a change to any of them can purge the response.
+func surrogateKeys(org Organisation) string {
+	keys := map[string]bool{allOrganisationsSurrogateKey: true, surrogateKey(canonicalUUID(org.ID)): true}
+	for _, uuid := range embeddedUUIDs(org) {
+		keys[surrogateKey(uuid)] = true
+	}
+	return strings.Join(sortedKeys(keys), " ")
+}
+
+// embeddedUUIDs are the UUIDs of the parent, subsidiaries and financial instrument the organisation embeds
+func embeddedUUIDs(org Organisation) []string {
+	var uuids []string
+	if org.Parent != nil {
+		uuids = append(uuids, canonicalUUID(org.Parent.ID))
+	}
+	for _, subsidiary := range org.Subsidiaries {
+		uuids = append(uuids, canonicalUUID(subsidiary.ID))
+	}
+	if org.FinancialInstrument != nil {
+		uuids = append(uuids, canonicalUUID(org.FinancialInstrument.ID))
+	}
+	return uuids
+}
+
+func surrogateKey(uuid string) string {
+	return surrogateKeyPrefix + uuid
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,121 @@
+package organisations
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCacheOrganisation(id string) Organisation {
+	return Organisation{Thing: Thing{ID: "http://api.ft.com/things/" + id, PrefLabel: "Nintendo Co Ltd"}}
+}
+
+func TestOrganisationCacheExpiresEntries(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewOrganisationCache(time.Minute, 0)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("7c5218a0-3755-463e-abbc-1a1632cfd1da", testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da"))
+	entry, ok := cache.Get("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(time.Minute), entry.ExpiresAt)
+
+	now = now.Add(time.Minute)
+	_, ok = cache.Get("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	assert.False(t, ok, "entries should expire after the ttl")
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Expired)
+	assert.Equal(t, 0, stats.Entries)
+}
+
+func TestOrganisationCacheEvictsWhenFull(t *testing.T) {
+	cache := NewOrganisationCache(time.Minute, 1)
+	cache.Set("7c5218a0-3755-463e-abbc-1a1632cfd1da", testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da"))
+	cache.Set("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", testCacheOrganisation("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6"))
+
+	stats := cache.Stats()
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, uint64(1), stats.Evicted)
+	_, ok := cache.Peek("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+	assert.True(t, ok, "the newest entry should be kept")
+}
+
+func TestOrganisationCachePurge(t *testing.T) {
+	cache := NewOrganisationCache(time.Minute, 0)
+	canonical := testCacheOrganisation("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+	cache.Set("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", canonical)
+	cache.Set("2d3e16e0-61cb-4322-8aff-3b01c59f4daa", canonical)
+	cache.Set("7c5218a0-3755-463e-abbc-1a1632cfd1da", testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da"))
+
+	purged, keys := cache.Purge("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+	assert.Equal(t, 2, purged, "entries requested by an alternate UUID should be purged with the canonical one")
+	assert.Equal(t, []string{"organisation-2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "organisation-d6b12f0c-bf3f-4045-a07b-1e4e49103fd6"}, keys)
+
+	purged, keys = cache.PurgePrefix("7c52")
+	assert.Equal(t, 1, purged)
+	assert.Equal(t, []string{"organisation-7c5218a0-3755-463e-abbc-1a1632cfd1da"}, keys)
+
+	cache.Set("7c5218a0-3755-463e-abbc-1a1632cfd1da", testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da"))
+	purged, keys = cache.Flush()
+	assert.Equal(t, 1, purged)
+	assert.Equal(t, []string{"organisations"}, keys)
+	assert.Equal(t, uint64(4), cache.Stats().Purged)
+}
+
+func TestOrganisationCacheIgnoresTheCaseOfUUIDs(t *testing.T) {
+	cache := NewOrganisationCache(time.Minute, 0)
+	cache.Set("7C5218A0-3755-463E-ABBC-1A1632CFD1DA", testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da"))
+	cache.Set("7c5218a0-3755-463e-abbc-1a1632cfd1da", testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da"))
+	assert.Equal(t, 1, cache.Stats().Entries, "an organisation requested in either case is cached once")
+
+	entry, ok := cache.Get("7C5218a0-3755-463e-abbc-1a1632cfd1da")
+	assert.True(t, ok)
+	assert.Equal(t, "7c5218a0-3755-463e-abbc-1a1632cfd1da", entry.UUID)
+
+	purged, keys := cache.PurgePrefix("7C52")
+	assert.Equal(t, 1, purged)
+	assert.Equal(t, []string{"organisation-7c5218a0-3755-463e-abbc-1a1632cfd1da"}, keys)
+
+	cache.Set("7C5218A0-3755-463E-ABBC-1A1632CFD1DA", testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da"))
+	purged, _ = cache.Purge("7C5218A0-3755-463E-ABBC-1A1632CFD1DA")
+	assert.Equal(t, 1, purged)
+}
+
+func TestGetOrganisationAnswersFromCache(t *testing.T) {
+	mockClient := &mockHTTPClient{resp: getCompleteOrganisationAsConcept, statusCode: 200}
+	cache := NewOrganisationCache(time.Minute, 0)
+	bh := NewHandler(mockClient, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"), WithCache(cache))
+	router := mux.NewRouter()
+	bh.RegisterHandlers(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	first := rec.Body.String()
+	assert.Contains(t, rec.Header().Get("Surrogate-Key"), "organisation-7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	assert.Contains(t, rec.Header().Get("Surrogate-Key"), "organisations")
+
+	mockClient.statusCode = http.StatusServiceUnavailable
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "a cached organisation should not be read again")
+	assert.Equal(t, first, rec.Body.String())
+	assert.Equal(t, uint64(1), cache.Stats().Hits)
+}
+
+func TestSurrogateKeysIncludeEmbeddedOrganisations(t *testing.T) {
+	org := testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	org.Parent = &Parent{Thing: Thing{ID: "http://api.ft.com/things/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6"}}
+	org.Subsidiaries = []Subsidiary{{Thing: Thing{ID: "http://api.ft.com/things/2d3e16e0-61cb-4322-8aff-3b01c59f4daa"}}}
+
+	assert.Equal(t, "organisation-2d3e16e0-61cb-4322-8aff-3b01c59f4daa organisation-7c5218a0-3755-463e-abbc-1a1632cfd1da "+
+		"organisation-d6b12f0c-bf3f-4045-a07b-1e4e49103fd6 organisations", surrogateKeys(org))
+}
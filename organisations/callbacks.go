@@ -0,0 +1,77 @@
+package organisations
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// allowCallback checks that webhooks may be delivered to host. Without CallbackHosts any host is allowed, unless it
+// is at an internal address: subscribers could otherwise have the service call the systems beside it.
+func (w *Webhooks) allowCallback(ctx context.Context, host string) error {
+	host = strings.ToLower(host)
+	if len(w.cfg.CallbackHosts) > 0 {
+		for _, allowed := range w.cfg.CallbackHosts {
+			if matchesHost(strings.ToLower(allowed), host) {
+				return nil
+			}
+		}
+		return fmt.Errorf("callbackURL host %s is not allowed", host)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if internalAddress(ip) {
+			return fmt.Errorf("callbackURL host %s is an internal address", host)
+		}
+		return nil
+	}
+	addresses, err := w.lookupIP(ctx, host)
+	if err != nil || len(addresses) == 0 {
+		return fmt.Errorf("callbackURL host %s cannot be resolved", host)
+	}
+	for _, address := range addresses {
+		if internalAddress(address.IP) {
+			return fmt.Errorf("callbackURL host %s resolves to an internal address", host)
+		}
+	}
+	return nil
+}
+
+// matchesHost reports whether host is allowed, *.example.com allowing every subdomain of example.com
+func matchesHost(allowed string, host string) bool {
+	if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == allowed
+}
+
+// internalAddress reports whether ip is only reachable from inside the network the service runs in
+func internalAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// NewWebhookClient creates the client delivering webhooks, each attempt taking up to timeout. Without callbackHosts
+// it refuses to connect to internal addresses, as the addresses of hosts checked on subscription can change.
+func NewWebhookClient(timeout time.Duration, callbackHosts []string) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if len(callbackHosts) == 0 {
+		dialer.Control = func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || internalAddress(ip) {
+				return fmt.Errorf("webhooks are not delivered to internal address %s", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout},
+	}
+}
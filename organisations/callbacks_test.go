@@ -0,0 +1,66 @@
+package organisations
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowCallbackWithHosts(t *testing.T) {
+	webhooks := &Webhooks{cfg: WebhookConfig{CallbackHosts: []string{"hooks.example.com", "*.ft.com", "127.0.0.1"}}, lookupIP: testLookupIP}
+	for host, allowed := range map[string]bool{
+		"hooks.example.com":       true,
+		"HOOKS.example.com":       true,
+		"company-pages.ft.com":    true,
+		"a.company-pages.ft.com":  true,
+		"127.0.0.1":               true,
+		"ft.com":                  false,
+		"evilft.com":              false,
+		"example.com":             false,
+		"hooks.example.com.evil.": false,
+	} {
+		err := webhooks.allowCallback(context.Background(), host)
+		assert.Equal(t, allowed, err == nil, host)
+	}
+}
+
+func TestAllowCallbackWithoutHosts(t *testing.T) {
+	webhooks := &Webhooks{lookupIP: testLookupIP}
+	for host, allowed := range map[string]bool{
+		"example.com":          true,
+		"93.184.215.14":        true,
+		"2606:2800:21f:cb07::": true,
+		"internal.example.com": false,
+		"unknown.example.com":  false,
+		"localhost":            false,
+		"127.0.0.1":            false,
+		"10.1.2.3":             false,
+		"192.168.0.1":          false,
+		"169.254.169.254":      false,
+		"0.0.0.0":              false,
+		"::1":                  false,
+		"fd00::1":              false,
+		"::ffff:127.0.0.1":     false,
+	} {
+		err := webhooks.allowCallback(context.Background(), host)
+		assert.Equal(t, allowed, err == nil, host)
+	}
+}
+
+func TestWebhookClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := NewWebhookClient(time.Second, nil).Get(server.URL)
+	require.Error(t, err, "hosts checked on subscription could resolve to internal addresses later")
+	assert.Contains(t, err.Error(), "internal address")
+
+	resp, err := NewWebhookClient(time.Second, []string{"127.0.0.1"}).Get(server.URL)
+	require.NoError(t, err, "listed hosts are trusted wherever they are")
+	resp.Body.Close()
+}
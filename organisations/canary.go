@@ -0,0 +1,84 @@
+package organisations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+// CanaryCheckID identifies the canary health check
+const CanaryCheckID = "canary-organisation-check"
+
+// CanaryCheck reads a known organisation through the whole lookup and transform used by /organisations/{uuid},
+// and checks the result has every required field and a sound type hierarchy
+func (h *OrganisationsHandler) CanaryCheck(uuid string) fthealth.Check {
+	return fthealth.Check{
+		ID:               CanaryCheckID,
+		BusinessImpact:   "Organisations may be served incomplete or not at all",
+		Name:             "Check a known organisation can be read and transformed",
+		PanicGuide:       "https://runbooks.in.ft.com/public-org-api",
+		Severity:         2,
+		TechnicalSummary: fmt.Sprintf("Organisation %s is read from public-concepts-api and transformed as for /organisations/%s. A failure means public-concepts-api no longer returns it complete, or the transform no longer understands it.", uuid, uuid),
+		Checker: func() (string, error) {
+			return h.checkCanary(uuid)
+		},
+	}
+}
+
+func (h *OrganisationsHandler) checkCanary(uuid string) (string, error) {
+	org, outcome, err := h.getOrganisationFromSource(context.Background(), uuid, transactionidutils.NewTransactionID())
+	if err != nil {
+		return "", fmt.Errorf("canary organisation %s could not be read: %w", uuid, err)
+	}
+	if outcome != outcomeFound {
+		return "", fmt.Errorf("canary organisation %s could not be read: %s", uuid, outcome)
+	}
+	if err = validateOrganisation(org); err != nil {
+		return "", fmt.Errorf("canary organisation %s is invalid: %w", uuid, err)
+	}
+	if canonical := canonicalUUID(org.ID); canonical != uuid {
+		return fmt.Sprintf("Canary organisation %s is complete, but has been concorded to %s", uuid, canonical), nil
+	}
+	return fmt.Sprintf("Canary organisation %s (%s) is complete", uuid, org.PrefLabel), nil
+}
+
+// validateOrganisation checks the fields every organisation must have, and that its types run from Thing
+// through Organisation down to its direct type
+func validateOrganisation(org Organisation) error {
+	missing := []string{}
+	for field, value := range map[string]string{
+		"id":         org.ID,
+		"apiUrl":     org.APIURL,
+		"prefLabel":  org.PrefLabel,
+		"directType": org.DirectType,
+	} {
+		if value == "" {
+			missing = append(missing, field)
+		}
+	}
+	if len(org.Types) == 0 {
+		missing = append(missing, "types")
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+
+	if org.Types[0] != ontologyPrefix+"/core/Thing" {
+		return fmt.Errorf("types start with %s instead of Thing", org.Types[0])
+	}
+	if last := org.Types[len(org.Types)-1]; last != org.DirectType {
+		return fmt.Errorf("types end with %s instead of the direct type %s", last, org.DirectType)
+	}
+	for _, t := range org.Types {
+		if t == ontologyPrefix+organisationSuffix {
+			return nil
+		}
+	}
+	return errors.New("types do not include Organisation")
+}
@@ -0,0 +1,91 @@
+package organisations
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanaryCheck(t *testing.T) {
+	tests := []struct {
+		name           string
+		uuid           string
+		clientCode     int
+		clientBody     string
+		clientError    error
+		expectedOutput string
+		expectedError  string
+	}{
+		{"Complete organisation", "7c5218a0-3755-463e-abbc-1a1632cfd1da", 200, getCompleteOrganisationAsConcept, nil,
+			"Canary organisation 7c5218a0-3755-463e-abbc-1a1632cfd1da (Nintendo Co Ltd) is complete", ""},
+		{"Concorded organisation", "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", 200, getRedirectedOrganisation, nil,
+			"Canary organisation 2d3e16e0-61cb-4322-8aff-3b01c59f4daa is complete, but has been concorded to d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", ""},
+		{"Not found", "7c5218a0-3755-463e-abbc-1a1632cfd1da", 404, "", nil,
+			"", "canary organisation 7c5218a0-3755-463e-abbc-1a1632cfd1da could not be read: not-found"},
+		{"Not an organisation", "f92a4ca4-84f9-11e8-8f42-da24cd01f044", 200, getPersonAsConcept, nil,
+			"", "canary organisation f92a4ca4-84f9-11e8-8f42-da24cd01f044 could not be read: not-organisation"},
+		{"Upstream error", "7c5218a0-3755-463e-abbc-1a1632cfd1da", 503, "", errors.New("connection refused"),
+			"", "canary organisation 7c5218a0-3755-463e-abbc-1a1632cfd1da could not be read: connection refused"},
+		{"Missing prefLabel", "7c5218a0-3755-463e-abbc-1a1632cfd1da", 200, `{
+			"id": "http://www.ft.com/thing/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+			"apiUrl": "http://api.ft.com/concepts/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+			"type": "http://www.ft.com/ontology/organisation/Organisation"
+		}`, nil, "", "canary organisation 7c5218a0-3755-463e-abbc-1a1632cfd1da is invalid: missing prefLabel"},
+	}
+
+	for _, test := range tests {
+		mockClient := &mockHTTPClient{resp: test.clientBody, statusCode: test.clientCode, err: test.clientError}
+		h := NewHandler(mockClient, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"))
+
+		check := h.CanaryCheck(test.uuid)
+		assert.Equal(t, CanaryCheckID, check.ID, test.name)
+		assert.Equal(t, uint8(2), check.Severity, test.name)
+
+		output, err := check.Checker()
+		if test.expectedError != "" {
+			assert.EqualError(t, err, test.expectedError, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expectedOutput, output, test.name)
+	}
+}
+
+func TestValidateOrganisation(t *testing.T) {
+	valid := func() Organisation {
+		return Organisation{
+			Thing: Thing{
+				ID:        "http://api.ft.com/things/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+				APIURL:    "http://api.ft.com/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+				PrefLabel: "Nintendo Co Ltd",
+			},
+			Types: []string{
+				"http://www.ft.com/ontology/core/Thing",
+				"http://www.ft.com/ontology/concept/Concept",
+				"http://www.ft.com/ontology/organisation/Organisation",
+				"http://www.ft.com/ontology/company/Company",
+			},
+			DirectType: "http://www.ft.com/ontology/company/Company",
+		}
+	}
+	assert.NoError(t, validateOrganisation(valid()))
+
+	org := valid()
+	org.ID = ""
+	org.Types = nil
+	assert.EqualError(t, validateOrganisation(org), "missing id, types")
+
+	org = valid()
+	org.Types = org.Types[1:]
+	assert.EqualError(t, validateOrganisation(org), "types start with http://www.ft.com/ontology/concept/Concept instead of Thing")
+
+	org = valid()
+	org.DirectType = "http://www.ft.com/ontology/company/PublicCompany"
+	assert.EqualError(t, validateOrganisation(org), "types end with http://www.ft.com/ontology/company/Company instead of the direct type http://www.ft.com/ontology/company/PublicCompany")
+
+	org = valid()
+	org.Types = []string{"http://www.ft.com/ontology/core/Thing", "http://www.ft.com/ontology/company/Company"}
+	assert.EqualError(t, validateOrganisation(org), "types do not include Organisation")
+}
@@ -0,0 +1,294 @@
+package organisations
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const definitionsRef = "#/definitions/"
+
+// ContractMode is what happens to an organisation that does not match the API contract
+type ContractMode string
+
+const (
+	// ContractReport logs and counts the violations and still returns the organisation
+	ContractReport ContractMode = "report"
+	// ContractEnforce logs and counts the violations and fails the request
+	ContractEnforce ContractMode = "enforce"
+)
+
+// ParseContractMode parses the contract validation mode, off disables validation and returns an empty mode
+func ParseContractMode(s string) (ContractMode, error) {
+	switch s {
+	case "", "off":
+		return "", nil
+	case string(ContractReport), string(ContractEnforce):
+		return ContractMode(s), nil
+	default:
+		return "", fmt.Errorf("contract validation mode '%s' is not one of off, report or enforce", s)
+	}
+}
+
+// Schema is the subset of the Swagger schema object the API contract is written with
+type Schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 string             `yaml:"type"`
+	Required             []string           `yaml:"required"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	AdditionalProperties *bool              `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	Enum                 []interface{}      `yaml:"enum"`
+	Pattern              string             `yaml:"pattern"`
+	MinItems             *int               `yaml:"minItems"`
+	Minimum              *float64           `yaml:"minimum"`
+
+	pattern *regexp.Regexp
+}
+
+// Violation is a way a response does not match the API contract
+type Violation struct {
+	// Path is the JSON pointer of the offending value
+	Path string `json:"path"`
+	// Rule is the schema keyword that was broken
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// ContractValidator checks responses against a schema of the Swagger API definition
+type ContractValidator struct {
+	definitions map[string]*Schema
+	root        *Schema
+	mode        ContractMode
+}
+
+// WithContractValidator validates every organisation against the API contract before it is returned
+func WithContractValidator(validator *ContractValidator) HandlerOption {
+	return func(h *OrganisationsHandler) {
+		h.contract = validator
+	}
+}
+
+// WithContractValidatorV2 validates every version 2 organisation against the API contract before it is returned
+func WithContractValidatorV2(validator *ContractValidator) HandlerOption {
+	return func(h *OrganisationsHandler) {
+		h.contractV2 = validator
+	}
+}
+
+// NewContractValidator validates against the definition of the Swagger API definition in spec
+func NewContractValidator(spec []byte, definition string, mode ContractMode) (*ContractValidator, error) {
+	var api struct {
+		Definitions map[string]*Schema `yaml:"definitions"`
+	}
+	if err := yaml.Unmarshal(spec, &api); err != nil {
+		return nil, fmt.Errorf("parsing API definition: %w", err)
+	}
+	v := &ContractValidator{definitions: api.Definitions, mode: mode}
+	for name, schema := range api.Definitions {
+		if err := v.compile(schema); err != nil {
+			return nil, fmt.Errorf("definition %s: %w", name, err)
+		}
+	}
+	root, ok := api.Definitions[definition]
+	if !ok {
+		return nil, fmt.Errorf("API definition has no %s definition", definition)
+	}
+	v.root = root
+	return v, nil
+}
+
+// compile checks the references of the schema resolve and compiles its patterns
+func (v *ContractValidator) compile(schema *Schema) error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref != "" {
+		if _, ok := v.definitions[strings.TrimPrefix(schema.Ref, definitionsRef)]; !ok {
+			return fmt.Errorf("reference %s is not defined", schema.Ref)
+		}
+	}
+	if schema.Pattern != "" {
+		pattern, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %s: %w", schema.Pattern, err)
+		}
+		schema.pattern = pattern
+	}
+	for _, property := range schema.Properties {
+		if err := v.compile(property); err != nil {
+			return err
+		}
+	}
+	return v.compile(schema.Items)
+}
+
+// Mode is what happens to invalid responses
+func (v *ContractValidator) Mode() ContractMode {
+	return v.mode
+}
+
+// Validate lists the violations of the contract by response, sorted by path
+func (v *ContractValidator) Validate(response interface{}) ([]Violation, error) {
+	b, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	violations := v.validate(v.root, value, "")
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Path < violations[j].Path
+	})
+	return violations, nil
+}
+
+func (v *ContractValidator) validate(schema *Schema, value interface{}, path string) []Violation {
+	if schema.Ref != "" {
+		return v.validate(v.definitions[strings.TrimPrefix(schema.Ref, definitionsRef)], value, path)
+	}
+	violation := func(rule string, format string, args ...interface{}) []Violation {
+		return []Violation{{Path: pointer(path), Rule: rule, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if schema.Type != "" && !hasType(value, schema.Type) {
+		return violation("type", "is %s, not %s", jsonType(value), schema.Type)
+	}
+	if len(schema.Enum) > 0 && !inEnum(value, schema.Enum) {
+		return violation("enum", "%v is not one of %v", value, schema.Enum)
+	}
+
+	var violations []Violation
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				violations = append(violations, violation("required", "%s is required", name)...)
+			}
+		}
+		for name, property := range value {
+			propertySchema, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					violations = append(violations, Violation{Path: pointer(path + "/" + name), Rule: "additionalProperties", Message: "is not defined"})
+				}
+				continue
+			}
+			violations = append(violations, v.validate(propertySchema, property, path+"/"+name)...)
+		}
+	case []interface{}:
+		if schema.MinItems != nil && len(value) < *schema.MinItems {
+			violations = append(violations, violation("minItems", "has %d items, fewer than %d", len(value), *schema.MinItems)...)
+		}
+		if schema.Items != nil {
+			for i, item := range value {
+				violations = append(violations, v.validate(schema.Items, item, fmt.Sprintf("%s/%d", path, i))...)
+			}
+		}
+	case string:
+		if schema.pattern != nil && !schema.pattern.MatchString(value) {
+			violations = append(violations, violation("pattern", "%q does not match %s", value, schema.Pattern)...)
+		}
+	case float64:
+		if schema.Minimum != nil && value < *schema.Minimum {
+			violations = append(violations, violation("minimum", "%v is less than %v", value, *schema.Minimum)...)
+		}
+	}
+	return violations
+}
+
+func pointer(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func hasType(value interface{}, schemaType string) bool {
+	switch schemaType {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == schemaType
+	}
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(value, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// meetsContract validates the organisation with uuid when contract is configured, logging and counting the
+// violations. It is false when the organisation must not be returned.
+func (h *OrganisationsHandler) meetsContract(contract *ContractValidator, uuid string, org interface{}, transID string) bool {
+	if contract == nil {
+		return true
+	}
+	violations, err := contract.Validate(org)
+	if err != nil {
+		h.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to validate organisation against the API contract")
+		return true
+	}
+	if len(violations) == 0 {
+		return true
+	}
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		h.metrics.contractViolations.WithLabelValues(violationField(violation.Path), violation.Rule).Inc()
+		messages = append(messages, violation.String())
+	}
+	h.logger.WithTransactionID(transID).
+		WithUUID(uuid).
+		WithField("violations", messages).
+		WithField("mode", string(contract.mode)).
+		Warn("organisation does not match the API contract")
+	return contract.mode != ContractEnforce
+}
+
+// violationField names the field of a violation for metrics, leaving out array indexes
+func violationField(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment != "" && strings.Trim(segment, "0123456789") == "" {
+			segments[i] = "*"
+		}
+	}
+	return strings.Join(segments, "/")
+}
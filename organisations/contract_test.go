@@ -0,0 +1,123 @@
+package organisations
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestContractValidator(t *testing.T, mode ContractMode) *ContractValidator {
+	return newTestContractValidatorOf(t, "Organisation", mode)
+}
+
+func newTestContractValidatorOf(t *testing.T, definition string, mode ContractMode) *ContractValidator {
+	spec, err := os.ReadFile("../_ft/api.yml")
+	require.NoError(t, err)
+	validator, err := NewContractValidator(spec, definition, mode)
+	require.NoError(t, err)
+	return validator
+}
+
+func TestContractValidatorAcceptsTransformedOrganisations(t *testing.T) {
+	validator := newTestContractValidator(t, ContractReport)
+	for _, fixture := range []string{getTransformedCompleteOrganisation, getTransformedCompleteDeprecatedOrganisation, getTransformedPrivateCompany} {
+		org := Organisation{}
+		require.NoError(t, json.Unmarshal([]byte(fixture), &org))
+		violations, err := validator.Validate(org)
+		require.NoError(t, err)
+		assert.Empty(t, violations)
+	}
+}
+
+func TestContractValidatorAcceptsTransformedOrganisationsV2(t *testing.T) {
+	validator := newTestContractValidatorOf(t, "OrganisationV2", ContractReport)
+	org := OrganisationV2{}
+	require.NoError(t, json.Unmarshal([]byte(getTransformedCompleteOrganisationV2), &org))
+	violations, err := validator.Validate(org)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestContractValidatorReportsViolations(t *testing.T) {
+	validator := newTestContractValidator(t, ContractReport)
+	org := Organisation{}
+	require.NoError(t, json.Unmarshal([]byte(getTransformedCompleteOrganisation), &org))
+	org.PrefLabel = ""
+	org.CountryCode = "Japan"
+	org.Parent.APIURL = "http://api.ft.com/concepts/335e9e5a-8f2e-11e8-8f42-da24cd01f044"
+	org.LabelDetails = []LabelDetail{{Type: "properName", Value: "Nintendo Co., Ltd."}, {Type: "nickname", Value: "Big N"}}
+
+	violations, err := validator.Validate(org)
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{
+		{Path: "/", Rule: "required", Message: "prefLabel is required"},
+		{Path: "/countryCode", Rule: "pattern", Message: `"Japan" does not match ^[A-Z]{2}$`},
+		{Path: "/labelDetails/1/type", Rule: "enum", Message: "nickname is not one of [properName shortName formerName alias hiddenLabel alternativeLabel]"},
+		{Path: "/parentOrganisation/apiUrl", Rule: "pattern", Message: `"http://api.ft.com/concepts/335e9e5a-8f2e-11e8-8f42-da24cd01f044" does not match ^http://api\.ft\.com/organisations/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`},
+	}, violations)
+
+	violations, err = validator.Validate(map[string]interface{}{"id": 42, "types": []string{}, "nickname": "Big N"})
+	require.NoError(t, err)
+	rules := []string{}
+	for _, violation := range violations {
+		rules = append(rules, violation.Path+" "+violation.Rule)
+	}
+	assert.ElementsMatch(t, []string{"/ required", "/ required", "/id type", "/nickname additionalProperties", "/types minItems"}, rules)
+}
+
+func TestNewContractValidatorRejectsInvalidDefinitions(t *testing.T) {
+	_, err := NewContractValidator([]byte("definitions: {}"), "Organisation", ContractReport)
+	assert.Error(t, err)
+	_, err = NewContractValidator([]byte("definitions: {Organisation: {$ref: '#/definitions/Thing'}}"), "Organisation", ContractReport)
+	assert.Error(t, err)
+	_, err = NewContractValidator([]byte("definitions: {Organisation: {type: string, pattern: '['}}"), "Organisation", ContractReport)
+	assert.Error(t, err)
+
+	_, err = ParseContractMode("strict")
+	assert.Error(t, err)
+}
+
+func TestContractModes(t *testing.T) {
+	for _, test := range []struct {
+		mode         ContractMode
+		expectedCode int
+	}{
+		{ContractReport, http.StatusOK},
+		{ContractEnforce, http.StatusInternalServerError},
+	} {
+		metrics := NewMetrics(prometheus.NewRegistry())
+		invalid := newTestMemorySource(t, `{
+			"id": "http://www.ft.com/thing/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6",
+			"apiUrl": "http://api.ft.com/concepts/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6",
+			"type": "http://www.ft.com/ontology/organisation/Organisation",
+			"countryCode": "Japan"
+		}`)
+		h := NewHandler(nil, "", logger.NewUPPInfoLogger("tests"), WithSource(invalid), WithMetrics(metrics),
+			WithContractValidator(newTestContractValidator(t, test.mode)),
+			WithContractValidatorV2(newTestContractValidatorOf(t, "OrganisationV2", test.mode)))
+		router := mux.NewRouter()
+		h.RegisterHandlers(router)
+
+		for i, url := range []string{"/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", "/v2/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6"} {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+			assert.Equal(t, test.expectedCode, rec.Code, "%s %s", test.mode, url)
+			assert.Equal(t, float64(i+1), testutil.ToFloat64(metrics.contractViolations.WithLabelValues("/countryCode", "pattern")), "%s %s", test.mode, url)
+			assert.Equal(t, float64(i+1), testutil.ToFloat64(metrics.contractViolations.WithLabelValues("/", "required")), "%s %s", test.mode, url)
+		}
+	}
+}
+
+func TestViolationField(t *testing.T) {
+	assert.Equal(t, "/subsidiaries/*/id", violationField("/subsidiaries/12/id"))
+	assert.Equal(t, "/", violationField("/"))
+}
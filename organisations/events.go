@@ -0,0 +1,247 @@
+package organisations
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+// consumeRetryDelay is how long the consumer waits before reading again after its message source failed
+const consumeRetryDelay = time.Second
+
+// Message is a message read from a MessageSource
+type Message struct {
+	Body          []byte
+	TransactionID string
+
+	offset int64
+}
+
+// MessageSource is a stream of concept change notifications
+type MessageSource interface {
+	// Next blocks until a message is available or ctx is done
+	Next(ctx context.Context) (Message, error)
+	// Commit marks the message, and those read before it, as processed. Sources without offsets to keep ignore it.
+	Commit(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// ConceptEvent is a notification that a concept changed. Concordance events also name the concept an identifier
+// moved from and to.
+type ConceptEvent struct {
+	Type          string `json:"type"`
+	UUID          string `json:"uuid"`
+	TransactionID string `json:"transactionID"`
+	EventDetails  struct {
+		Type  string `json:"eventType"`
+		OldID string `json:"oldID"`
+		NewID string `json:"newID"`
+	} `json:"eventDetails"`
+}
+
+// uuids are the UUIDs of the concepts the event changed
+func (e ConceptEvent) uuids() []string {
+	var uuids []string
+	for _, id := range []string{e.UUID, e.EventDetails.OldID, e.EventDetails.NewID} {
+		if uuid := canonicalUUID(id); uuid != "" {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return uuids
+}
+
+// ChangeConsumer keeps the organisation cache up to date with concept change notifications. It either invalidates
+// the organisations affected by a change, or refreshes them from public-concepts-api.
+type ChangeConsumer struct {
+	handler *OrganisationsHandler
+	source  MessageSource
+	refresh bool
+}
+
+// NewChangeConsumer creates a consumer of the notifications from source for the cache of handler, which must have one
+func NewChangeConsumer(handler *OrganisationsHandler, source MessageSource, refresh bool) *ChangeConsumer {
+	return &ChangeConsumer{handler: handler, source: source, refresh: refresh}
+}
+
+// Run consumes notifications until ctx is done
+func (c *ChangeConsumer) Run(ctx context.Context) {
+	for {
+		msg, err := c.source.Next(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			c.handler.logger.WithError(err).Error("failed to read concept change notification")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(consumeRetryDelay):
+			}
+			continue
+		}
+		c.handle(ctx, msg)
+		if err := c.source.Commit(ctx, msg); err != nil && ctx.Err() == nil {
+			c.handler.logger.WithTransactionID(msg.TransactionID).WithError(err).Error("failed to commit concept change notification")
+		}
+	}
+}
+
+func (c *ChangeConsumer) handle(ctx context.Context, msg Message) {
+	var event ConceptEvent
+	err := json.Unmarshal(msg.Body, &event)
+	if err == nil && len(event.uuids()) == 0 {
+		err = errors.New("notification names no concept")
+	}
+	if err != nil {
+		c.handler.metrics.conceptEvents.WithLabelValues("invalid").Inc()
+		c.handler.logger.WithTransactionID(msg.TransactionID).WithError(err).Warn("ignoring invalid concept change notification")
+		return
+	}
+	transID := msg.TransactionID
+	if transID == "" {
+		transID = event.TransactionID
+	}
+	if transID == "" {
+		transID = transactionidutils.NewTransactionID()
+	}
+
+	changed := event.uuids()
+	keys := c.handler.cache.Embedding(changed)
+	if len(keys) == 0 {
+		c.handler.metrics.conceptEvents.WithLabelValues("ignored").Inc()
+		return
+	}
+	c.handler.metrics.conceptEvents.WithLabelValues("applied").Inc()
+
+	done := map[string]bool{}
+	for len(keys) > 0 {
+		key := keys[0]
+		keys = keys[1:]
+		if done[key] {
+			continue
+		}
+		done[key] = true
+		// A changed organisation may have started embedding others, whose cached entries do not embed it yet
+		keys = append(keys, c.handler.cache.KeysFor(c.update(ctx, key, changed, transID))...)
+	}
+
+	surrogateKeys := make([]string, 0, len(changed))
+	for _, uuid := range changed {
+		surrogateKeys = append(surrogateKeys, surrogateKey(uuid))
+	}
+	c.handler.logger.WithTransactionID(transID).
+		WithField("conceptType", event.Type).
+		WithField("uuids", strings.Join(changed, " ")).
+		WithField("updated", len(done)).
+		WithField("surrogateKeys", strings.Join(surrogateKeys, " ")).
+		Info("applied concept change notification to organisation cache")
+}
+
+// update invalidates or refreshes the entry cached for key. When key is one of the changed organisations and was
+// refreshed, it returns the UUIDs the new version embeds.
+func (c *ChangeConsumer) update(ctx context.Context, key string, changed []string, transID string) []string {
+	if !c.refresh {
+		c.handler.cache.Purge(key)
+		c.handler.metrics.cacheUpdates.WithLabelValues("invalidated").Inc()
+		return nil
+	}
+	org, outcome, err := c.handler.getOrganisationFromSource(ctx, key, transID)
+	if err != nil || outcome != outcomeFound {
+		c.handler.cache.Purge(key)
+		c.handler.metrics.cacheUpdates.WithLabelValues("invalidated").Inc()
+		return nil
+	}
+	c.handler.cache.Set(key, org)
+	c.handler.metrics.cacheUpdates.WithLabelValues("refreshed").Inc()
+	// Requests are served the refreshed version from the cache, so it is recorded now
+	c.handler.recordHistory(org, transID)
+	c.handler.notifyWebhooks(org, transID)
+	for _, uuid := range changed {
+		if key == uuid || canonicalUUID(org.ID) == uuid {
+			return embeddedUUIDs(org)
+		}
+	}
+	return nil
+}
+
+// parseFTMessage splits a message in the FT message format, a FTMSG/1.0 line and headers before the body, into
+// its transaction ID and body. Other messages are returned as they are.
+func parseFTMessage(raw []byte) (transID string, body []byte) {
+	if !bytes.HasPrefix(raw, []byte("FTMSG/")) {
+		return "", raw
+	}
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw)))
+	if _, err := reader.ReadLine(); err != nil {
+		return "", raw
+	}
+	headers, err := reader.ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", raw
+	}
+	if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 {
+		body = raw[i+4:]
+	} else if i := bytes.Index(raw, []byte("\n\n")); i >= 0 {
+		body = raw[i+2:]
+	}
+	return headers.Get(transactionidutils.TransactionIDHeader), body
+}
+
+// MemorySource is a MessageSource kept in memory, for tests
+type MemorySource struct {
+	messages chan Message
+
+	mu        sync.Mutex
+	published int64
+	committed []int64
+}
+
+// NewMemorySource creates a source holding up to capacity unread messages
+func NewMemorySource(capacity int) *MemorySource {
+	return &MemorySource{messages: make(chan Message, capacity)}
+}
+
+// Publish adds a message, in the FT message format or not
+func (s *MemorySource) Publish(raw []byte) {
+	transID, body := parseFTMessage(raw)
+	s.mu.Lock()
+	offset := s.published
+	s.published++
+	s.mu.Unlock()
+	s.messages <- Message{Body: body, TransactionID: transID, offset: offset}
+}
+
+func (s *MemorySource) Next(ctx context.Context) (Message, error) {
+	select {
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	case msg := <-s.messages:
+		return msg, nil
+	}
+}
+
+func (s *MemorySource) Commit(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.committed = append(s.committed, msg.offset)
+	return nil
+}
+
+// Committed returns the offsets of the messages committed so far, in order
+func (s *MemorySource) Committed() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64(nil), s.committed...)
+}
+
+func (s *MemorySource) Close() error {
+	return nil
+}
@@ -0,0 +1,102 @@
+package organisations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func runTestConsumer(t *testing.T, h *OrganisationsHandler, refresh bool, messages ...string) *MemorySource {
+	source := NewMemorySource(len(messages))
+	for _, msg := range messages {
+		source.Publish([]byte(msg))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go NewChangeConsumer(h, source, refresh).Run(ctx)
+
+	assert.Eventually(t, func() bool { return len(source.Committed()) == len(messages) }, time.Second, time.Millisecond)
+	return source
+}
+
+func TestChangeConsumerInvalidatesAffectedOrganisations(t *testing.T) {
+	cache := NewOrganisationCache(time.Minute, 0)
+	subsidiary := testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	subsidiary.Parent = &Parent{Thing: Thing{ID: "http://api.ft.com/things/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6"}}
+	parent := testCacheOrganisation("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+	parent.Subsidiaries = []Subsidiary{{Thing: Thing{ID: "http://api.ft.com/things/7c5218a0-3755-463e-abbc-1a1632cfd1da"}}}
+	cache.Set("7c5218a0-3755-463e-abbc-1a1632cfd1da", subsidiary)
+	cache.Set("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", parent)
+	cache.Set("2d3e16e0-61cb-4322-8aff-3b01c59f4daa", testCacheOrganisation("2d3e16e0-61cb-4322-8aff-3b01c59f4daa"))
+	h := NewHandler(&mockHTTPClient{}, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"), WithCache(cache))
+
+	source := runTestConsumer(t, &h, false,
+		"FTMSG/1.0\r\nX-Request-Id: tid_test\r\n\r\n"+`{"type": "PublicCompany", "uuid": "7c5218a0-3755-463e-abbc-1a1632cfd1da", "eventDetails": {"eventType": "Concept Updated"}}`,
+		`not json`,
+		`{"type": "Person", "uuid": "f92a4ca4-84f9-11e8-8f42-da24cd01f044"}`,
+	)
+
+	assert.Equal(t, []int64{0, 1, 2}, source.Committed(), "every message should be committed, including invalid ones")
+	_, ok := cache.Peek("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	assert.False(t, ok, "the changed organisation should be invalidated")
+	_, ok = cache.Peek("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+	assert.False(t, ok, "the parent embedding the changed organisation should be invalidated")
+	_, ok = cache.Peek("2d3e16e0-61cb-4322-8aff-3b01c59f4daa")
+	assert.True(t, ok, "unrelated organisations should be kept")
+}
+
+func TestChangeConsumersOfEveryInstanceInvalidateTheirCache(t *testing.T) {
+	event := `{"type": "PublicCompany", "uuid": "7c5218a0-3755-463e-abbc-1a1632cfd1da"}`
+	caches := []*OrganisationCache{NewOrganisationCache(time.Minute, 0), NewOrganisationCache(time.Minute, 0)}
+	for _, cache := range caches {
+		cache.Set("7c5218a0-3755-463e-abbc-1a1632cfd1da", testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da"))
+		h := NewHandler(&mockHTTPClient{}, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"), WithCache(cache))
+		runTestConsumer(t, &h, false, event)
+	}
+
+	for i, cache := range caches {
+		_, ok := cache.Peek("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+		assert.False(t, ok, "instance %d should invalidate the changed organisation", i)
+	}
+}
+
+func TestChangeConsumerRefreshesAffectedOrganisations(t *testing.T) {
+	cache := NewOrganisationCache(time.Minute, 0)
+	cache.Set("7c5218a0-3755-463e-abbc-1a1632cfd1da", testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da"))
+	cache.Set("2d3e16e0-61cb-4322-8aff-3b01c59f4daa", testCacheOrganisation("2d3e16e0-61cb-4322-8aff-3b01c59f4daa"))
+	mockClient := &mockHTTPClient{resp: getCompleteOrganisationAsConcept, statusCode: 200}
+	h := NewHandler(mockClient, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"), WithCache(cache))
+
+	runTestConsumer(t, &h, true,
+		`{"type": "Concordance Transferred", "uuid": "7c5218a0-3755-463e-abbc-1a1632cfd1da", "eventDetails": {"eventType": "Concordance Transferred", "oldID": "f92a4ca4-84f9-11e8-8f42-da24cd01f044", "newID": "7c5218a0-3755-463e-abbc-1a1632cfd1da"}}`,
+	)
+
+	entry, ok := cache.Peek("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	assert.True(t, ok, "the changed organisation should stay cached")
+	assert.NotNil(t, entry.Organisation.Parent, "the changed organisation should be read again")
+	assert.Equal(t, uint64(0), cache.Stats().Purged)
+}
+
+func TestChangeConsumerInvalidatesWhenRefreshFails(t *testing.T) {
+	cache := NewOrganisationCache(time.Minute, 0)
+	cache.Set("7c5218a0-3755-463e-abbc-1a1632cfd1da", testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da"))
+	h := NewHandler(&mockHTTPClient{statusCode: 404}, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"), WithCache(cache))
+
+	runTestConsumer(t, &h, true, `{"type": "PublicCompany", "uuid": "7c5218a0-3755-463e-abbc-1a1632cfd1da"}`)
+
+	_, ok := cache.Peek("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	assert.False(t, ok)
+}
+
+func TestParseFTMessage(t *testing.T) {
+	transID, body := parseFTMessage([]byte("FTMSG/1.0\nMessage-Id: 1\nX-Request-Id: tid_test\n\n{\"uuid\": \"x\"}"))
+	assert.Equal(t, "tid_test", transID)
+	assert.Equal(t, `{"uuid": "x"}`, string(body))
+
+	transID, body = parseFTMessage([]byte(`{"uuid": "x"}`))
+	assert.Equal(t, "", transID)
+	assert.Equal(t, `{"uuid": "x"}`, string(body))
+}
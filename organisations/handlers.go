@@ -1,20 +1,23 @@
 package organisations
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	ontology "github.com/Financial-Times/cm-graph-ontology"
 	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
 	"github.com/Financial-Times/go-logger/v2"
 	"github.com/Financial-Times/service-status-go/gtg"
 	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
-	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type HTTPClient interface {
@@ -22,44 +25,105 @@ type HTTPClient interface {
 }
 
 type OrganisationsHandler struct {
-	client      HTTPClient
-	conceptsURL string
-	logger      *logger.UPPLogger
+	source     OrganisationSource
+	logger     *logger.UPPLogger
+	typeFilter TypeFilter
+	history    HistoryStore
+	metrics    *Metrics
+	cache      *OrganisationCache
+	webhooks   *Webhooks
+	contract   *ContractValidator
+	contractV2 *ContractValidator
+	formats    *ResponseFormats
+	auth       *Authenticator
 }
 
-// OrganisationDriver for cypher queries
-var CacheControlHeader string
+// HandlerOption configures optional behaviour of an OrganisationsHandler
+type HandlerOption func(*OrganisationsHandler)
+
+// WithTypeFilter restricts which organisation types the handler serves
+func WithTypeFilter(filter TypeFilter) HandlerOption {
+	return func(h *OrganisationsHandler) {
+		h.typeFilter = filter
+	}
+}
+
+var cacheControlHeader atomic.Value
+
+// SetCacheControlHeader sets the Cache-Control header of organisation responses. It is safe to call while serving requests.
+func SetCacheControlHeader(header string) {
+	cacheControlHeader.Store(header)
+}
+
+func cacheControl() string {
+	header, _ := cacheControlHeader.Load().(string)
+	return header
+}
 
 const (
-	validUUID           = "([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$"
-	ontologyPrefix      = "http://www.ft.com/ontology"
-	organisationSuffix  = "/organisation/Organisation"
-	publicCompanySuffix = "/company/PublicCompany"
-	relatedQueryParam   = "?showRelationship=related"
-	isParentPredicate   = "/parentOrganisationOf"
-	hasParentPredicate  = "/subOrganisationOf"
-	issuedPredicate     = "/issued"
-	thingsApiUrl        = "http://api.ft.com/things/"
-	ftThing             = "http://www.ft.com/thing/"
+	validUUID          = "([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$"
+	ontologyPrefix     = "http://www.ft.com/ontology"
+	organisationSuffix = "/organisation/Organisation"
+	relatedQueryParam  = "?showRelationship=related"
+	isParentPredicate  = "/parentOrganisationOf"
+	hasParentPredicate = "/subOrganisationOf"
+	issuedPredicate    = "/issued"
+	thingsApiUrl       = "http://api.ft.com/things/"
+	ftThing            = "http://www.ft.com/thing/"
 )
 
-func NewHandler(client HTTPClient, conceptsURL string, ftLogger *logger.UPPLogger) OrganisationsHandler {
-	return OrganisationsHandler{
-		client,
-		conceptsURL,
-		ftLogger,
+func NewHandler(client HTTPClient, conceptsURL string, ftLogger *logger.UPPLogger, opts ...HandlerOption) OrganisationsHandler {
+	h := OrganisationsHandler{
+		logger:     ftLogger,
+		typeFilter: NewTypeFilter(nil, nil),
+		metrics:    NewMetrics(prometheus.NewRegistry()),
+		formats:    NewResponseFormats(JSONFormat),
 	}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	if h.source == nil {
+		h.source = NewConceptsAPISource(client, conceptsURL, ftLogger, h.metrics)
+	}
+	if h.webhooks != nil {
+		// Webhooks carry organisations too, redacted for each subscriber
+		h.webhooks.auth = h.auth
+	}
+	return h
 }
 
 func (h *OrganisationsHandler) RegisterHandlers(router *mux.Router) {
 	h.logger.Info("Registering handlers")
-	mh := handlers.MethodHandler{
-		"GET": http.HandlerFunc(h.GetOrganisation),
-	}
+	// Paths and methods no route matches are answered with problems too, rather than with gorilla's plain text
+	router.NotFoundHandler = http.HandlerFunc(h.NotFoundHandler)
+	router.MethodNotAllowedHandler = http.HandlerFunc(h.MethodNotAllowedHandler)
 
 	path := "/organisations/{uuid}"
-	router.Handle(path, mh)
+	router.Handle(path, methodHandler{
+		"GET": h.countVersion(apiV1, path, h.GetOrganisation),
+	})
 	router.HandleFunc(path, h.MethodNotAllowedHandler)
+
+	router.Handle("/organisations", methodHandler{
+		"GET": h.countVersion(apiV1, "/organisations", h.LookupOrganisations),
+	})
+	router.HandleFunc("/organisations", h.MethodNotAllowedHandler)
+
+	identifiersPath := "/organisations/{uuid}/identifiers"
+	router.Handle(identifiersPath, methodHandler{
+		"GET": h.countVersion(apiV1, identifiersPath, h.GetIdentifiers),
+	})
+	router.HandleFunc(identifiersPath, h.MethodNotAllowedHandler)
+
+	if h.history != nil {
+		historyPath := "/organisations/{uuid}/history"
+		router.Handle(historyPath, methodHandler{
+			"GET": h.countVersion(apiV1, historyPath, h.GetHistory),
+		})
+		router.HandleFunc(historyPath, h.MethodNotAllowedHandler)
+	}
+
+	h.registerV2Handlers(router)
 }
 
 // HealthCheck does something
@@ -77,25 +141,7 @@ func (h *OrganisationsHandler) HealthCheck() fthealth.Check {
 
 // Checker does more stuff
 func (h *OrganisationsHandler) Checker() (string, error) {
-	req, err := http.NewRequest("GET", h.conceptsURL+"/__gtg", nil)
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Add("User-Agent", "UPP public-organisations-api")
-
-	resp, err := h.client.Do(req)
-	if err != nil {
-		return "", err
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("health check returned a non-200 HTTP status: %v", resp.StatusCode)
-	}
-	return "Public Concepts API is healthy", nil
-
+	return h.source.Check()
 }
 
 // Ping says pong
@@ -110,8 +156,12 @@ func (h *OrganisationsHandler) BuildInfoHandler(w http.ResponseWriter, req *http
 
 // MethodNotAllowedHandler does stuff
 func (h *OrganisationsHandler) MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusMethodNotAllowed)
-	return
+	writeProblem(w, r, http.StatusMethodNotAllowed, errorMethodNotAllowed, fmt.Sprintf("method %s is not allowed", r.Method))
+}
+
+// NotFoundHandler answers requests for paths the API does not serve
+func (h *OrganisationsHandler) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusNotFound, errorNotFound, fmt.Sprintf("%s was not found", r.URL.Path))
 }
 
 // GetOrganisation is the public API
@@ -120,44 +170,82 @@ func (h *OrganisationsHandler) GetOrganisation(w http.ResponseWriter, r *http.Re
 	vars := mux.Vars(r)
 	uuid := vars["uuid"]
 	transID := transactionidutils.GetTransactionIDFromRequest(r)
-
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	ctx, span := tracer.Start(r.Context(), "GetOrganisation", trace.WithAttributes(
+		attribute.String("organisation.uuid", uuid),
+		attribute.String(transactionIDAttribute, transID),
+	))
+	defer span.End()
+
+	format, ok := h.negotiateFormat(w, r)
+	if !ok {
+		h.observeRequest(span, http.StatusNotAcceptable, outcomeNotAcceptable)
+		writeProblem(w, r, http.StatusNotAcceptable, errorNotAcceptable, notAcceptableMessage(h.formats))
+		return
+	}
 	if uuid == "" || !uuidMatcher.MatchString(uuid) {
 		msg := fmt.Sprintf(`uuid '%s' is either missing or invalid`, uuid)
 		h.logger.WithTransactionID(transID).WithUUID(uuid).Error(msg)
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"message": "` + msg + `"}`))
+		h.observeRequest(span, http.StatusBadRequest, outcomeInvalidUUID)
+		writeProblem(w, r, http.StatusBadRequest, errorInvalidUUID, msg)
 		return
 	}
 
-	organisation, found, err := h.getOrganisationViaConceptsAPI(uuid, transID)
+	organisation, outcome, cached, err := h.getOrganisation(ctx, uuid, transID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"message": "failed to return organisation"}`))
+		h.observeRequest(span, http.StatusInternalServerError, outcome)
+		writeUpstreamProblem(w, r, "failed to return organisation", err)
 		return
 	}
-	if !found {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte(`{"message": "organisation not found"}`))
+	if outcome != outcomeFound {
+		h.observeRequest(span, http.StatusNotFound, outcome)
+		writeProblem(w, r, http.StatusNotFound, errorNotFound, "organisation not found")
 		return
 	}
-	//if the request was not made for the canonical, but an alternate uuid: redirect
-	if !strings.Contains(organisation.ID, uuid) {
-		validRegexp := regexp.MustCompile(validUUID)
-		canonicalUUID := validRegexp.FindString(organisation.ID)
-		redirectURL := strings.Replace(r.RequestURI, uuid, canonicalUUID, 1)
-		w.Header().Set("Location", redirectURL)
-		w.WriteHeader(http.StatusMovedPermanently)
+	if redirectToCanonical(w, r, uuid, organisation.ID) {
+		h.observeRequest(span, http.StatusMovedPermanently, outcomeRedirect)
 		return
 	}
+	if !cached {
+		// A cached organisation is a version already seen
+		h.recordHistory(organisation, transID)
+		h.notifyWebhooks(organisation, transID)
+	}
+	if r.URL.Query().Get(showLabelDetailsParam) != "true" {
+		organisation.LabelDetails = nil
+	}
+	if !h.meetsContract(h.contract, canonicalUUID(organisation.ID), organisation, transID) {
+		h.observeRequest(span, http.StatusInternalServerError, outcomeContractViolation)
+		writeProblem(w, r, http.StatusInternalServerError, errorContractViolation, "organisation does not match the API contract")
+		return
+	}
+	h.redact(w, r, &organisation)
+
+	w.Header().Set("Cache-Control", cacheControl())
+	w.Header().Set("Surrogate-Key", surrogateKeys(organisation))
+	if err = writeEncoded(w, format, http.StatusOK, organisation); err != nil {
+		h.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to encode organisation")
+		h.observeRequest(span, http.StatusInternalServerError, outcomeEncodingError)
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "organisation could not be encoded")
+		return
+	}
+	h.observeRequest(span, http.StatusOK, outcomeFound)
+}
 
-	w.Header().Set("Cache-Control", CacheControlHeader)
-	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(organisation)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"message":"Organisation could not be marshelled, err=` + err.Error() + `"}`))
+// redirectToCanonical writes a redirect when uuid is an alternate rather than the canonical UUID in id,
+// and reports whether it did so
+func redirectToCanonical(w http.ResponseWriter, r *http.Request, uuid string, id string) bool {
+	if strings.Contains(id, uuid) {
+		return false
 	}
+	redirectURL := strings.Replace(r.RequestURI, uuid, canonicalUUID(id), 1)
+	w.Header().Set("Location", redirectURL)
+	w.WriteHeader(http.StatusMovedPermanently)
+	return true
+}
+
+func canonicalUUID(id string) string {
+	validRegexp := regexp.MustCompile(validUUID)
+	return validRegexp.FindString(id)
 }
 
 // GoodToGo returns a 503 if the healthcheck fails - suitable for use from varnish to check availability of a node
@@ -175,58 +263,59 @@ func gtgCheck(handler func() (string, error)) gtg.Status {
 	return gtg.Status{GoodToGo: true}
 }
 
-func (h *OrganisationsHandler) getOrganisationViaConceptsAPI(uuid string, transID string) (organisation Organisation, found bool, err error) {
-	log := h.logger.WithTransactionID(transID).WithUUID(uuid)
-	org := Organisation{}
-
-	reqURL := h.conceptsURL + "/concepts/" + uuid + relatedQueryParam
+// Organisation reads and transforms the organisation with the UUID as GetOrganisation does, without the cache.
+// found is false if the source does not know the UUID or it is not an organisation.
+func (h *OrganisationsHandler) Organisation(ctx context.Context, uuid string, transID string) (organisation Organisation, found bool, err error) {
+	organisation, outcome, err := h.getOrganisationFromSource(ctx, uuid, transID)
+	return organisation, outcome == outcomeFound, err
+}
 
-	request, err := http.NewRequest("GET", reqURL, nil)
+func (h *OrganisationsHandler) getOrganisationFromSource(ctx context.Context, uuid string, transID string) (organisation Organisation, outcome lookupOutcome, err error) {
+	ctx, span := tracer.Start(ctx, "getOrganisationFromSource", trace.WithAttributes(attribute.String("organisation.uuid", uuid)))
+	defer func() {
+		endSpan(span, outcome, err)
+	}()
 
-	if err != nil {
-		msg := fmt.Sprintf("failed to create request to %s", reqURL)
-		log.WithError(err).Error(msg)
-		return org, false, err
+	conceptsApiResponse, types, outcome, err := h.organisationConcept(ctx, uuid, transID)
+	if outcome != outcomeFound {
+		return Organisation{}, outcome, err
 	}
 
-	request.Header.Set("X-Request-Id", transID)
-	resp, err := h.client.Do(request)
+	_, transformSpan := tracer.Start(ctx, "transformOrganisation")
+	start := time.Now()
+	org, err := transformOrganisation(conceptsApiResponse, types)
+	h.metrics.transformDuration.Observe(time.Since(start).Seconds())
+	transformSpan.End()
 	if err != nil {
-		msg := fmt.Sprintf("request to %s was unsuccessful", reqURL)
-		log.WithError(err).Error(msg)
-		return org, false, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusNotFound {
-		return org, false, nil
+		h.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to transform organisation")
+		return Organisation{}, outcomeUpstreamError, err
 	}
+	h.metrics.observeUnknowns(conceptsApiResponse)
 
-	conceptsApiResponse := ConceptApiResponse{}
-	body, err := ioutil.ReadAll(resp.Body)
+	return org, outcomeFound, nil
+}
 
+// organisationConcept reads the concept with the UUID and its related concepts from the source, together with its
+// type hierarchy. The outcome is found only if it is an organisation the handler serves.
+func (h *OrganisationsHandler) organisationConcept(ctx context.Context, uuid string, transID string) (ConceptApiResponse, []string, lookupOutcome, error) {
+	concept, found, err := h.source.Concept(ctx, uuid, transID, true)
 	if err != nil {
-		msg := fmt.Sprintf("failed to read response body: %v", resp.Body)
-		log.WithError(err).Error(msg)
-		return org, false, err
+		return ConceptApiResponse{}, nil, outcomeUpstreamError, err
 	}
-
-	if err = json.Unmarshal(body, &conceptsApiResponse); err != nil {
-		msg := fmt.Sprintf("failed to unmarshal response body: %v", body)
-		log.WithError(err).Error(msg)
-		return org, false, err
+	if !found {
+		return ConceptApiResponse{}, nil, outcomeNotFound, nil
 	}
 
-	if conceptsApiResponse.Type != ontologyPrefix+organisationSuffix && conceptsApiResponse.Type != ontologyPrefix+publicCompanySuffix {
-		log.Info("requested concept is not a organisation")
-		return org, false, nil
+	types, found := h.organisationTypes(concept, transID)
+	if !found {
+		return ConceptApiResponse{}, nil, outcomeNotOrganisation, nil
 	}
+	return concept, types, outcomeFound, nil
+}
 
-	types, err := ontology.FullTypeHierarchy(conceptsApiResponse.Type)
-	if err != nil {
-		log.WithError(err).WithField("type", conceptsApiResponse.Type).Error("getting type hierarchy")
-		return org, false, fmt.Errorf("getting type hierarchy: %w", err)
-	}
+// transformOrganisation converts a concept with the given type hierarchy to the public organisation model
+func transformOrganisation(conceptsApiResponse ConceptApiResponse, types []string) (Organisation, error) {
+	org := Organisation{}
 
 	org.ID = convertID(conceptsApiResponse.ID)
 	org.APIURL = convertApiUrl(conceptsApiResponse.ApiURL, "organisations")
@@ -267,6 +356,9 @@ func (h *OrganisationsHandler) getOrganisationViaConceptsAPI(uuid string, transI
 	if len(uniqLabel) > 0 {
 		org.Labels = uniqLabel
 	}
+	if len(conceptsApiResponse.AlternativeLabels) > 0 {
+		org.LabelDetails = transformLabelDetails(conceptsApiResponse.AlternativeLabels)
+	}
 
 	var subsidiaries = []Subsidiary{}
 	for _, item := range conceptsApiResponse.Related {
@@ -274,13 +366,7 @@ func (h *OrganisationsHandler) getOrganisationViaConceptsAPI(uuid string, transI
 
 		types, err := ontology.FullTypeHierarchy(c.Type)
 		if err != nil {
-			log.WithError(err).
-				WithFields(map[string]interface{}{
-					"type":        conceptsApiResponse.Type,
-					"relatedUUID": c.ID,
-				}).
-				Error("getting type hierarchy for related concept")
-			return Organisation{}, false, fmt.Errorf("getting type hierarchy: %w", err)
+			return Organisation{}, fmt.Errorf("getting type hierarchy for related concept %s of type %s: %w", c.ID, c.Type, err)
 		}
 
 		if strings.TrimPrefix(item.Predicate, ontologyPrefix) == hasParentPredicate {
@@ -316,7 +402,25 @@ func (h *OrganisationsHandler) getOrganisationViaConceptsAPI(uuid string, transI
 		org.Subsidiaries = subsidiaries
 	}
 
-	return org, true, nil
+	return org, nil
+}
+
+// organisationTypes returns the type hierarchy of the concept, found is false if the concept is not a organisation
+// the handler serves
+func (h *OrganisationsHandler) organisationTypes(concept ConceptApiResponse, transID string) (types []string, found bool) {
+	log := h.logger.WithTransactionID(transID).WithUUID(concept.ID)
+
+	types, err := ontology.FullTypeHierarchy(concept.Type)
+	if err != nil {
+		log.WithError(err).WithField("type", concept.Type).Warn("requested concept has a type outside of the ontology")
+		return nil, false
+	}
+
+	if !h.typeFilter.Accepts(types) {
+		log.WithField("type", concept.Type).Info("requested concept is not a organisation")
+		return nil, false
+	}
+	return types, true
 }
 
 func convertApiUrl(conceptsApiUrl string, desired string) string {
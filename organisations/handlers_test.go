@@ -56,7 +56,7 @@ func TestHandlers(t *testing.T) {
 		getBasicOrganisationAsConcept,
 		nil,
 		400,
-		`{"message": "uuid '1234' is either missing or invalid"}`,
+		`{"type": "about:blank", "title": "Bad Request", "status": 400, "detail": "uuid '1234' is either missing or invalid", "code": "invalid-uuid", "transactionId": "tid_test"}`,
 	}
 	conceptApiError := testCase{
 		"Get organisations - Concepts API Error results in error",
@@ -65,7 +65,16 @@ func TestHandlers(t *testing.T) {
 		"",
 		errors.New("Downstream error"),
 		500,
-		`{"message": "failed to return organisation"}`,
+		`{"type": "about:blank", "title": "Internal Server Error", "status": 500, "detail": "failed to return organisation", "code": "upstream-error", "transactionId": "tid_test"}`,
+	}
+	conceptApiFailureStatus := testCase{
+		"Get organisations - Concepts API failure status is returned",
+		"/organisations/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+		503,
+		`{"id": "http://www.ft.com/thing/2d3e16e0-61cb-4322-8aff-3b01c59f4daa"}`,
+		nil,
+		500,
+		`{"type": "about:blank", "title": "Internal Server Error", "status": 500, "detail": "failed to return organisation", "code": "upstream-error", "transactionId": "tid_test", "upstreamStatus": 503}`,
 	}
 	redirectedUUID := testCase{
 		"Get organisations - Given UUID was not canonical",
@@ -83,7 +92,7 @@ func TestHandlers(t *testing.T) {
 		`{`,
 		nil,
 		500,
-		`{"message": "failed to return organisation"}`,
+		`{"type": "about:blank", "title": "Internal Server Error", "status": 500, "detail": "failed to return organisation", "code": "upstream-error", "transactionId": "tid_test"}`,
 	}
 	notFound := testCase{
 		"Get organisation - not found",
@@ -92,7 +101,7 @@ func TestHandlers(t *testing.T) {
 		"",
 		nil,
 		404,
-		`{"message": "organisation not found"}`,
+		`{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "organisation not found", "code": "not-found", "transactionId": "tid_test"}`,
 	}
 	nonOrganisationsReturnsNotFound := testCase{
 		"Get organisation - Other type returns not found",
@@ -101,7 +110,7 @@ func TestHandlers(t *testing.T) {
 		getPersonAsConcept,
 		nil,
 		404,
-		`{"message": "organisation not found"}`,
+		`{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "organisation not found", "code": "not-found", "transactionId": "tid_test"}`,
 	}
 
 	deprecatedConcept := testCase{
@@ -124,15 +133,27 @@ func TestHandlers(t *testing.T) {
 		getTransformedCompleteOrganisation,
 	}
 
+	privateCompany := testCase{
+		"Get organisation - Subtypes of organisation are accepted",
+		"/organisations/a4ab4a5c-2b1e-4e1b-9b4c-0e9b4d1e7c11",
+		200,
+		getPrivateCompanyAsConcept,
+		nil,
+		200,
+		getTransformedPrivateCompany,
+	}
+
 	testCases := []testCase{
 		invalidUUID,
 		conceptApiError,
+		conceptApiFailureStatus,
 		redirectedUUID,
 		errorOnInvalidJson,
 		notFound,
 		nonOrganisationsReturnsNotFound,
 		successfulRequest,
 		deprecatedConcept,
+		privateCompany,
 	}
 
 	for _, test := range testCases {
@@ -144,15 +165,20 @@ func TestHandlers(t *testing.T) {
 
 		rr := httptest.NewRecorder()
 		req, _ := http.NewRequest("GET", test.url, nil)
+		req.Header.Set("X-Request-Id", "tid_test")
 
 		router.ServeHTTP(rr, req)
 		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
 
-		if rr.Code == 200 {
+		switch {
+		case rr.Code == 200:
 			assert.Equal(t, transformBody(test.expectedBody), rr.Body.String(), test.name+" failed: status body does not match!")
-			continue
+		case test.expectedBody == "":
+			assert.Empty(t, rr.Body.String(), test.name+" failed: status body does not match!")
+		default:
+			assert.Equal(t, problemMediaType, rr.Header().Get("Content-Type"), test.name+" failed: content type does not match!")
+			assert.JSONEq(t, test.expectedBody, rr.Body.String(), test.name+" failed: status body does not match!")
 		}
-		assert.Equal(t, test.expectedBody, rr.Body.String(), test.name+" failed: status body does not match!")
 	}
 }
 
@@ -163,7 +189,7 @@ func TestHeadersOKOnFoundForCanonicalNode(t *testing.T) {
 	mockClient.statusCode = 200
 	mockClient.err = nil
 
-	CacheControlHeader = expectedCacheControlHeader
+	SetCacheControlHeader(expectedCacheControlHeader)
 
 	router := mux.NewRouter()
 	bh := NewHandler(&mockClient, "localhost:8080/concepts", log)
@@ -179,6 +205,21 @@ func TestHeadersOKOnFoundForCanonicalNode(t *testing.T) {
 	assert.Equal(t, "application/json; charset=UTF-8", rec.Header().Get("Content-Type"))
 }
 
+func TestDeniedTypesReturnNotFound(t *testing.T) {
+	log := logger.NewUPPInfoLogger("tests")
+	mockClient := mockHTTPClient{resp: getPrivateCompanyAsConcept, statusCode: 200}
+
+	router := mux.NewRouter()
+	bh := NewHandler(&mockClient, "localhost:8080/concepts", log, WithTypeFilter(NewTypeFilter(nil, []string{"company/PrivateCompany"})))
+	bh.RegisterHandlers(router)
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/organisations/a4ab4a5c-2b1e-4e1b-9b4c-0e9b4d1e7c11", nil)
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
 func transformBody(testBody string) string {
 	stripNewLines := strings.Replace(testBody, "\n", "", -1)
 	stripTabs := strings.Replace(stripNewLines, "\t", "", -1)
@@ -199,6 +240,27 @@ var getRedirectedOrganisation = `{
 	"prefLabel": "Google Inc"
 }`
 
+var getPrivateCompanyAsConcept = `{
+	"id": "http://www.ft.com/thing/a4ab4a5c-2b1e-4e1b-9b4c-0e9b4d1e7c11",
+	"apiUrl": "http://api.ft.com/concepts/a4ab4a5c-2b1e-4e1b-9b4c-0e9b4d1e7c11",
+	"type": "http://www.ft.com/ontology/company/PrivateCompany",
+	"prefLabel": "Acme Holdings Ltd"
+}`
+
+var getTransformedPrivateCompany = `{
+	"id":"http://api.ft.com/things/a4ab4a5c-2b1e-4e1b-9b4c-0e9b4d1e7c11",
+	"apiUrl":"http://api.ft.com/organisations/a4ab4a5c-2b1e-4e1b-9b4c-0e9b4d1e7c11",
+	"prefLabel":"Acme Holdings Ltd",
+	"types":[
+		"http://www.ft.com/ontology/core/Thing",
+		"http://www.ft.com/ontology/concept/Concept",
+		"http://www.ft.com/ontology/organisation/Organisation",
+		"http://www.ft.com/ontology/company/Company",
+		"http://www.ft.com/ontology/company/PrivateCompany"
+	],
+	"directType":"http://www.ft.com/ontology/company/PrivateCompany"
+}`
+
 var getPersonAsConcept = `{
 	"id": "http://www.ft.com/thing/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
 	"apiUrl": "http://api.ft.com/concepts/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
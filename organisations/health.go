@@ -0,0 +1,206 @@
+package organisations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
+	"github.com/Financial-Times/service-status-go/gtg"
+)
+
+// HealthPoller runs the health checks on a schedule, so that /__health and /__gtg answer from the latest
+// results instead of calling upstream services on every request
+type HealthPoller struct {
+	health          fthealth.HealthCheck
+	interval        time.Duration
+	timeout         time.Duration
+	degradedLatency time.Duration
+
+	mu          sync.RWMutex
+	results     []CheckResult
+	notCritical map[string]bool
+}
+
+// CheckResult is the FT health check result extended with how long the check took, and whether it was
+// slower than the degraded latency threshold
+type CheckResult struct {
+	fthealth.CheckResult
+	DurationMs int64 `json:"durationMs"`
+	Degraded   bool  `json:"degraded,omitempty"`
+}
+
+// HealthResult is the FT health check response with the extended check results
+type HealthResult struct {
+	fthealth.HealthResult
+	Checks   []CheckResult `json:"checks"`
+	Degraded bool          `json:"degraded,omitempty"`
+}
+
+// NewHealthPoller polls the checks of health every interval. Each check is failed if it takes longer than
+// timeout, and degraded if it succeeds but takes longer than degradedLatency. Zero disables the degraded state.
+func NewHealthPoller(health fthealth.HealthCheck, interval time.Duration, timeout time.Duration, degradedLatency time.Duration) *HealthPoller {
+	results := make([]CheckResult, len(health.Checks))
+	for i, check := range health.Checks {
+		results[i] = CheckResult{CheckResult: newCheckResult(check)}
+		results[i].CheckOutput = "Check has not run yet"
+	}
+	return &HealthPoller{
+		health:          health,
+		interval:        interval,
+		timeout:         timeout,
+		degradedLatency: degradedLatency,
+		results:         results,
+	}
+}
+
+// Run polls the checks straight away and then every interval until ctx is done
+func (p *HealthPoller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.Poll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll runs every check in parallel and stores the results
+func (p *HealthPoller) Poll() {
+	results := make([]CheckResult, len(p.health.Checks))
+	wg := sync.WaitGroup{}
+	for i, check := range p.health.Checks {
+		wg.Add(1)
+		go func(i int, check fthealth.Check) {
+			defer wg.Done()
+			results[i] = p.runCheck(check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	p.results = results
+	p.mu.Unlock()
+}
+
+func (p *HealthPoller) runCheck(check fthealth.Check) CheckResult {
+	type outcome struct {
+		output string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- outcome{err: fmt.Errorf("check panicked: %v", rec)}
+			}
+		}()
+		output, err := check.Checker()
+		done <- outcome{output, err}
+	}()
+
+	var o outcome
+	select {
+	case o = <-done:
+	case <-time.After(p.timeout):
+		o = outcome{err: fmt.Errorf("timed out after %v", p.timeout)}
+	}
+	duration := time.Since(start)
+
+	result := CheckResult{CheckResult: newCheckResult(check), DurationMs: duration.Milliseconds()}
+	result.LastUpdated = start
+	if o.err != nil {
+		result.CheckOutput = o.err.Error()
+		return result
+	}
+	result.Ok = true
+	result.CheckOutput = o.output
+	if p.degradedLatency > 0 && duration > p.degradedLatency {
+		result.Degraded = true
+		result.CheckOutput = fmt.Sprintf("%s (degraded: took %v, over the %v threshold)", o.output, duration.Round(time.Millisecond), p.degradedLatency)
+	}
+	return result
+}
+
+func newCheckResult(check fthealth.Check) fthealth.CheckResult {
+	return fthealth.CheckResult{
+		ID:               check.ID,
+		Name:             check.Name,
+		Severity:         check.Severity,
+		BusinessImpact:   check.BusinessImpact,
+		TechnicalSummary: check.TechnicalSummary,
+		PanicGuide:       check.PanicGuide,
+		PanicGuideIsLink: strings.HasPrefix(check.PanicGuide, "http"),
+	}
+}
+
+// Result returns the latest results of the checks
+func (p *HealthPoller) Result() HealthResult {
+	p.mu.RLock()
+	checks := make([]CheckResult, len(p.results))
+	copy(checks, p.results)
+	p.mu.RUnlock()
+
+	result := HealthResult{
+		HealthResult: fthealth.HealthResult{
+			SchemaVersion: 1,
+			SystemCode:    p.health.SystemCode,
+			Name:          p.health.Name,
+			Description:   p.health.Description,
+		},
+		Checks: checks,
+	}
+	for _, check := range checks {
+		result.HealthResult.Checks = append(result.HealthResult.Checks, check.CheckResult)
+		result.Degraded = result.Degraded || check.Degraded
+	}
+	result.Ok = fthealth.ComputeOverallStatus(&result.HealthResult)
+	if !result.Ok {
+		result.Severity = fthealth.ComputeOverallSeverity(&result.HealthResult)
+	}
+	return result
+}
+
+// HealthHandler serves the latest results of the checks
+func (p *HealthPoller) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(p.Result()); err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "health could not be encoded")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body.Bytes())
+}
+
+// ExcludeFromGTG stops the checks with the given IDs from failing /__gtg. They are still reported by /__health.
+func (p *HealthPoller) ExcludeFromGTG(ids ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.notCritical == nil {
+		p.notCritical = map[string]bool{}
+	}
+	for _, id := range ids {
+		p.notCritical[id] = true
+	}
+}
+
+// GTG is good to go when every check not excluded from it passed the last time it ran
+func (p *HealthPoller) GTG() gtg.Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, result := range p.results {
+		if !result.Ok && !p.notCritical[result.ID] {
+			return gtg.Status{GoodToGo: false, Message: result.CheckOutput}
+		}
+	}
+	return gtg.Status{GoodToGo: true}
+}
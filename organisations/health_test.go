@@ -0,0 +1,149 @@
+package organisations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testHealthCheck(checkers ...func() (string, error)) fthealth.HealthCheck {
+	health := fthealth.HealthCheck{SystemCode: "public-org-api", Name: "PublicOrganisationsRead Healthcheck"}
+	for i, checker := range checkers {
+		health.Checks = append(health.Checks, fthealth.Check{
+			ID:         "check-" + string(rune('a'+i)),
+			Name:       "Test check",
+			PanicGuide: "https://runbooks.in.ft.com/public-org-api",
+			Severity:   uint8(i + 1),
+			Checker:    checker,
+		})
+	}
+	return health
+}
+
+func TestHealthPollerFailsBeforeFirstPoll(t *testing.T) {
+	poller := NewHealthPoller(testHealthCheck(func() (string, error) { return "ok", nil }), time.Minute, time.Second, 0)
+
+	result := poller.Result()
+	assert.False(t, result.Ok)
+	assert.Equal(t, "Check has not run yet", result.Checks[0].CheckOutput)
+	assert.False(t, poller.GTG().GoodToGo)
+}
+
+func TestHealthPollerServesCachedResults(t *testing.T) {
+	calls := 0
+	poller := NewHealthPoller(testHealthCheck(func() (string, error) {
+		calls++
+		return "Public Concepts API is healthy", nil
+	}), time.Minute, time.Second, 0)
+	poller.Poll()
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		poller.HealthHandler(rec, httptest.NewRequest("GET", "/__health", nil))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		body := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, true, body["ok"])
+		check := body["checks"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "Public Concepts API is healthy", check["checkOutput"])
+		assert.Contains(t, check, "durationMs")
+		assert.Contains(t, check, "lastUpdated")
+		assert.NotContains(t, check, "degraded")
+	}
+	assert.True(t, poller.GTG().GoodToGo)
+	assert.Equal(t, 1, calls, "checks only run when polled")
+}
+
+func TestHealthPollerReportsFailures(t *testing.T) {
+	poller := NewHealthPoller(testHealthCheck(
+		func() (string, error) { return "ok", nil },
+		func() (string, error) { return "", errors.New("health check returned a non-200 HTTP status: 503") },
+		func() (string, error) { panic("boom") },
+	), time.Minute, time.Second, 0)
+	poller.Poll()
+
+	result := poller.Result()
+	assert.False(t, result.Ok)
+	assert.Equal(t, uint8(2), result.Severity)
+	assert.True(t, result.Checks[0].Ok)
+	assert.Equal(t, "health check returned a non-200 HTTP status: 503", result.Checks[1].CheckOutput)
+	assert.Equal(t, "check panicked: boom", result.Checks[2].CheckOutput)
+	assert.Equal(t, "health check returned a non-200 HTTP status: 503", poller.GTG().Message)
+}
+
+func TestHealthPollerTimesOutChecks(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	poller := NewHealthPoller(testHealthCheck(func() (string, error) {
+		<-release
+		return "ok", nil
+	}), time.Minute, 10*time.Millisecond, 0)
+	poller.Poll()
+
+	result := poller.Result()
+	assert.False(t, result.Checks[0].Ok)
+	assert.Equal(t, "timed out after 10ms", result.Checks[0].CheckOutput)
+}
+
+func TestHealthPollerMarksSlowChecksDegraded(t *testing.T) {
+	poller := NewHealthPoller(testHealthCheck(func() (string, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "Public Concepts API is healthy", nil
+	}), time.Minute, time.Second, 5*time.Millisecond)
+	poller.Poll()
+
+	result := poller.Result()
+	assert.True(t, result.Ok, "degraded checks still pass")
+	assert.True(t, result.Degraded)
+	assert.True(t, result.Checks[0].Degraded)
+	assert.Contains(t, result.Checks[0].CheckOutput, "degraded")
+	assert.GreaterOrEqual(t, result.Checks[0].DurationMs, int64(20))
+	assert.True(t, poller.GTG().GoodToGo)
+}
+
+func TestHealthPollerRunStopsWithContext(t *testing.T) {
+	polled := make(chan struct{}, 10)
+	poller := NewHealthPoller(testHealthCheck(func() (string, error) {
+		select {
+		case polled <- struct{}{}:
+		default:
+		}
+		return "ok", nil
+	}), time.Millisecond, time.Second, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		poller.Run(ctx)
+		close(stopped)
+	}()
+	<-polled
+	<-polled
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("poller did not stop")
+	}
+}
+
+func TestHealthPollerExcludesChecksFromGTG(t *testing.T) {
+	poller := NewHealthPoller(testHealthCheck(
+		func() (string, error) { return "ok", nil },
+		func() (string, error) { return "", errors.New("canary organisation is invalid") },
+	), time.Minute, time.Second, 0)
+	poller.ExcludeFromGTG("check-b")
+	poller.Poll()
+
+	assert.False(t, poller.Result().Ok)
+	assert.True(t, poller.GTG().GoodToGo)
+}
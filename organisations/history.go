@@ -0,0 +1,278 @@
+package organisations
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
+
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/gorilla/mux"
+	bolt "go.etcd.io/bbolt"
+)
+
+var historyBucket = []byte("organisations")
+
+// HistoryStore keeps every distinct version of the organisations the handler has served
+type HistoryStore interface {
+	// Record saves the organisation unless it is identical to the latest version, and reports whether it was saved
+	Record(org Organisation, at time.Time) (recorded bool, err error)
+	// Versions returns the saved versions of an organisation, oldest first
+	Versions(uuid string) ([]Snapshot, error)
+	Close() error
+}
+
+// WithHistoryStore records every distinct organisation served and exposes the change history of each
+func WithHistoryStore(store HistoryStore) HandlerOption {
+	return func(h *OrganisationsHandler) {
+		h.history = store
+	}
+}
+
+// BoltHistoryStore is a HistoryStore kept in an embedded bbolt database, one bucket per organisation
+type BoltHistoryStore struct {
+	db *bolt.DB
+}
+
+// NewBoltHistoryStore opens, or creates, the history database at path
+func NewBoltHistoryStore(path string) (*BoltHistoryStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening history database %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(historyBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating history bucket: %w", err)
+	}
+	return &BoltHistoryStore{db: db}, nil
+}
+
+func (s *BoltHistoryStore) Record(org Organisation, at time.Time) (bool, error) {
+	uuid := canonicalUUID(org.ID)
+	if uuid == "" {
+		return false, fmt.Errorf("organisation id %s has no uuid", org.ID)
+	}
+	current, err := json.Marshal(org)
+	if err != nil {
+		return false, err
+	}
+
+	// Most organisations are as they were last recorded, which a read-only transaction is enough to tell
+	changed := false
+	err = s.db.View(func(tx *bolt.Tx) error {
+		changed, err = isNewVersion(tx.Bucket(historyBucket).Bucket([]byte(uuid)), current)
+		return err
+	})
+	if err != nil || !changed {
+		return false, err
+	}
+
+	recorded := false
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(historyBucket).CreateBucketIfNotExists([]byte(uuid))
+		if err != nil {
+			return err
+		}
+		// Another request may have recorded the version since
+		if changed, err := isNewVersion(b, current); err != nil || !changed {
+			return err
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		snapshot, err := json.Marshal(Snapshot{Timestamp: at.UTC(), Organisation: org})
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		recorded = true
+		return b.Put(key, snapshot)
+	})
+	return recorded, err
+}
+
+// isNewVersion reports whether the organisation, marshalled as current, differs from the latest version in the bucket
+// of its versions, which is nil before the first is recorded
+func isNewVersion(b *bolt.Bucket, current []byte) (bool, error) {
+	if b == nil {
+		return true, nil
+	}
+	_, v := b.Cursor().Last()
+	if v == nil {
+		return true, nil
+	}
+	latest := Snapshot{}
+	if err := json.Unmarshal(v, &latest); err != nil {
+		return false, err
+	}
+	previous, err := json.Marshal(latest.Organisation)
+	if err != nil {
+		return false, err
+	}
+	return string(previous) != string(current), nil
+}
+
+func (s *BoltHistoryStore) Versions(uuid string) ([]Snapshot, error) {
+	snapshots := []Snapshot{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(historyBucket).Bucket([]byte(uuid))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(_, v []byte) error {
+			snapshot := Snapshot{}
+			if err := json.Unmarshal(v, &snapshot); err != nil {
+				return err
+			}
+			snapshots = append(snapshots, snapshot)
+			return nil
+		})
+	})
+	return snapshots, err
+}
+
+func (s *BoltHistoryStore) Close() error {
+	return s.db.Close()
+}
+
+func (h *OrganisationsHandler) recordHistory(org Organisation, transID string) {
+	if h.history == nil {
+		return
+	}
+	recorded, err := h.history.Record(org, time.Now())
+	if err != nil {
+		h.logger.WithTransactionID(transID).WithUUID(org.ID).WithError(err).Error("failed to record organisation history")
+		return
+	}
+	if recorded {
+		h.logger.WithTransactionID(transID).WithUUID(org.ID).Info("recorded new version of organisation")
+	}
+}
+
+// GetHistory returns the recorded versions of an organisation with the fields that changed in each
+func (h *OrganisationsHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	uuidMatcher := regexp.MustCompile(validUUID)
+	uuid := mux.Vars(r)["uuid"]
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+
+	if uuid == "" || !uuidMatcher.MatchString(uuid) {
+		msg := fmt.Sprintf(`uuid '%s' is either missing or invalid`, uuid)
+		h.logger.WithTransactionID(transID).WithUUID(uuid).Error(msg)
+		writeProblem(w, r, http.StatusBadRequest, errorInvalidUUID, msg)
+		return
+	}
+
+	snapshots, err := h.history.Versions(uuid)
+	if err != nil {
+		h.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to read organisation history")
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "failed to return organisation history")
+		return
+	}
+	if len(snapshots) == 0 {
+		// History is recorded for canonical UUIDs, alternate ones are redirected to the history of their organisation
+		identifiers, found, err := h.getIdentifiersFromSource(r.Context(), uuid, transID)
+		if err != nil {
+			writeUpstreamProblem(w, r, "failed to return organisation history", err)
+			return
+		}
+		if !found || !redirectToCanonical(w, r, uuid, identifiers.ID) {
+			writeProblem(w, r, http.StatusNotFound, errorNotFound, "organisation history not found")
+		}
+		return
+	}
+
+	organisations := make([]interface{}, len(snapshots))
+	for i := range snapshots {
+		organisations[i] = &snapshots[i].Organisation
+	}
+	h.redact(w, r, organisations...)
+
+	history, err := transformHistory(snapshots)
+	if err != nil {
+		h.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to diff organisation history")
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "failed to return organisation history")
+		return
+	}
+
+	if err = writeEncoded(w, JSONFormat, http.StatusOK, history); err != nil {
+		h.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to encode organisation history")
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "organisation history could not be encoded")
+	}
+}
+
+func transformHistory(snapshots []Snapshot) (History, error) {
+	latest := snapshots[len(snapshots)-1].Organisation
+	history := History{ID: latest.ID, APIURL: latest.APIURL + "/history"}
+
+	var previous map[string]interface{}
+	for _, snapshot := range snapshots {
+		current, err := organisationFields(snapshot.Organisation)
+		if err != nil {
+			return History{}, err
+		}
+		version := Version{Timestamp: snapshot.Timestamp, Organisation: snapshot.Organisation}
+		if previous != nil {
+			version.Changes = diffFields(previous, current)
+		}
+		history.Versions = append(history.Versions, version)
+		previous = current
+	}
+	return history, nil
+}
+
+// DiffOrganisations lists the top level fields that differ between two organisations
+func DiffOrganisations(previous Organisation, current Organisation) ([]FieldChange, error) {
+	previousFields, err := organisationFields(previous)
+	if err != nil {
+		return nil, err
+	}
+	currentFields, err := organisationFields(current)
+	if err != nil {
+		return nil, err
+	}
+	return diffFields(previousFields, currentFields), nil
+}
+
+// organisationFields flattens an organisation to its top level JSON fields so versions can be compared field by field
+func organisationFields(org Organisation) (map[string]interface{}, error) {
+	b, err := json.Marshal(org)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	err = json.Unmarshal(b, &fields)
+	return fields, err
+}
+
+func diffFields(previous map[string]interface{}, current map[string]interface{}) []FieldChange {
+	names := map[string]bool{}
+	for name := range previous {
+		names[name] = true
+	}
+	for name := range current {
+		names[name] = true
+	}
+
+	changes := []FieldChange{}
+	for name := range names {
+		if reflect.DeepEqual(previous[name], current[name]) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, Previous: previous[name], Current: current[name]})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
@@ -0,0 +1,149 @@
+package organisations
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHistoryStore(t *testing.T) *BoltHistoryStore {
+	store, err := NewBoltHistoryStore(filepath.Join(t.TempDir(), "history.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestBoltHistoryStoreRecordsDistinctVersions(t *testing.T) {
+	store := newTestHistoryStore(t)
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	org := Organisation{
+		Thing:                 Thing{ID: "http://api.ft.com/things/7c5218a0-3755-463e-abbc-1a1632cfd1da", PrefLabel: "Nintendo Co Ltd"},
+		LegalEntityIdentifier: "353800FEEXU6I9M0ZF27",
+	}
+
+	recorded, err := store.Record(org, at)
+	require.NoError(t, err)
+	assert.True(t, recorded)
+
+	stats := store.db.Stats()
+	writes := stats.TxStats.GetWrite()
+	recorded, err = store.Record(org, at.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, recorded, "an unchanged organisation should not be recorded again")
+	stats = store.db.Stats()
+	assert.Equal(t, writes, stats.TxStats.GetWrite(), "an unchanged organisation should not be written")
+
+	org.PrefLabel = "Nintendo"
+	recorded, err = store.Record(org, at.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.True(t, recorded)
+
+	versions, err := store.Versions("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, at, versions[0].Timestamp)
+	assert.Equal(t, "Nintendo", versions[1].Organisation.PrefLabel)
+
+	versions, err = store.Versions("2d3e16e0-61cb-4322-8aff-3b01c59f4daa")
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestTransformHistoryDiffsFields(t *testing.T) {
+	previous := Organisation{
+		Thing:                 Thing{ID: "http://api.ft.com/things/7c5218a0-3755-463e-abbc-1a1632cfd1da", PrefLabel: "Nintendo Co Ltd"},
+		LegalEntityIdentifier: "353800FEEXU6I9M0ZF27",
+	}
+	current := previous
+	current.PrefLabel = "Nintendo"
+	current.LegalEntityIdentifier = ""
+	current.Parent = &Parent{Thing: Thing{ID: "http://api.ft.com/things/335e9e5a-8f2e-11e8-8f42-da24cd01f044"}}
+
+	history, err := transformHistory([]Snapshot{{Organisation: previous}, {Organisation: current}})
+	require.NoError(t, err)
+	require.Len(t, history.Versions, 2)
+	assert.Empty(t, history.Versions[0].Changes)
+
+	fields := []string{}
+	for _, change := range history.Versions[1].Changes {
+		fields = append(fields, change.Field)
+	}
+	assert.Equal(t, []string{"leiCode", "parentOrganisation", "prefLabel"}, fields)
+	assert.Equal(t, "353800FEEXU6I9M0ZF27", history.Versions[1].Changes[0].Previous)
+	assert.Nil(t, history.Versions[1].Changes[0].Current)
+}
+
+func TestGetHistory(t *testing.T) {
+	log := logger.NewUPPInfoLogger("tests")
+	mockClient := mockHTTPClient{resp: getBasicOrganisationAsConcept, statusCode: 200}
+	router := mux.NewRouter()
+	bh := NewHandler(&mockClient, "localhost:8080/concepts", log, WithHistoryStore(newTestHistoryStore(t)))
+	bh.RegisterHandlers(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6/history", nil))
+	assert.Equal(t, 404, rec.Code)
+
+	for i := 0; i < 2; i++ {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", nil))
+		assert.Equal(t, 200, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6/history", nil))
+	assert.Equal(t, 200, rec.Code)
+
+	history := History{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &history))
+	assert.Equal(t, "http://api.ft.com/things/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", history.ID)
+	assert.Len(t, history.Versions, 1)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/organisations/7f3bbbbd-0b5c-3b6b-9b4a-2e5f8c2f6b1e/history", nil))
+	assert.Equal(t, 301, rec.Code, "the history of an alternate UUID is that of its organisation")
+	assert.Equal(t, "/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6/history", rec.Header().Get("Location"))
+}
+
+func TestHistoryIsRecordedOnCacheMisses(t *testing.T) {
+	mockClient := mockHTTPClient{resp: getBasicOrganisationAsConcept, statusCode: 200}
+	cache := NewOrganisationCache(time.Minute, 0)
+	history := newTestHistoryStore(t)
+	h := NewHandler(&mockClient, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"), WithCache(cache), WithHistoryStore(history))
+	router := mux.NewRouter()
+	h.RegisterHandlers(router)
+	get := func() {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", nil))
+		require.Equal(t, 200, rec.Code)
+	}
+
+	get()
+	mockClient.resp = strings.Replace(getBasicOrganisationAsConcept, "Google Inc", "Alphabet Inc", 1)
+	get()
+	versions, err := history.Versions("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+	require.NoError(t, err)
+	assert.Len(t, versions, 1, "organisations served from the cache are not recorded again")
+
+	cache.Purge("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+	get()
+	versions, err = history.Versions("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "Alphabet Inc", versions[1].Organisation.PrefLabel)
+
+	mockClient.resp = strings.Replace(getBasicOrganisationAsConcept, "Google Inc", "Google LLC", 1)
+	runTestConsumer(t, &h, true, `{"type": "Organisation", "uuid": "d6b12f0c-bf3f-4045-a07b-1e4e49103fd6"}`)
+	versions, err = history.Versions("d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+	require.NoError(t, err)
+	require.Len(t, versions, 3, "organisations refreshed in the cache are recorded")
+	assert.Equal(t, "Google LLC", versions[2].Organisation.PrefLabel)
+}
@@ -0,0 +1,108 @@
+package organisations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/gorilla/mux"
+)
+
+const (
+	uppAuthority = "http://api.ft.com/system/UPP"
+	leiAuthority = "http://api.ft.com/system/LEI"
+)
+
+// GetIdentifiers returns the canonical UUID, alternate UUIDs and authority identifiers of an organisation
+func (h *OrganisationsHandler) GetIdentifiers(w http.ResponseWriter, r *http.Request) {
+	uuidMatcher := regexp.MustCompile(validUUID)
+	uuid := mux.Vars(r)["uuid"]
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+
+	if uuid == "" || !uuidMatcher.MatchString(uuid) {
+		msg := fmt.Sprintf(`uuid '%s' is either missing or invalid`, uuid)
+		h.logger.WithTransactionID(transID).WithUUID(uuid).Error(msg)
+		writeProblem(w, r, http.StatusBadRequest, errorInvalidUUID, msg)
+		return
+	}
+
+	identifiers, found, err := h.getIdentifiersFromSource(r.Context(), uuid, transID)
+	if err != nil {
+		writeUpstreamProblem(w, r, "failed to return organisation identifiers", err)
+		return
+	}
+	if !found {
+		writeProblem(w, r, http.StatusNotFound, errorNotFound, "organisation not found")
+		return
+	}
+	if redirectToCanonical(w, r, uuid, identifiers.ID) {
+		return
+	}
+
+	identifiers.Identifiers = withoutAuthorities(identifiers.Identifiers, h.hiddenAuthorities(w, r))
+	w.Header().Set("Cache-Control", cacheControl())
+	if err = writeEncoded(w, JSONFormat, http.StatusOK, identifiers); err != nil {
+		h.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to encode organisation identifiers")
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "organisation identifiers could not be encoded")
+	}
+}
+
+func (h *OrganisationsHandler) getIdentifiersFromSource(ctx context.Context, uuid string, transID string) (identifiers Identifiers, found bool, err error) {
+	concept, found, err := h.source.Concept(ctx, uuid, transID, false)
+	if err != nil || !found {
+		return Identifiers{}, false, err
+	}
+	if _, found = h.organisationTypes(concept, transID); !found {
+		return Identifiers{}, false, nil
+	}
+	return transformIdentifiers(concept), true, nil
+}
+
+// withoutAuthorities leaves out the identifiers of the hidden authorities
+func withoutAuthorities(identifiers []Identifier, hidden map[string]bool) []Identifier {
+	if len(hidden) == 0 {
+		return identifiers
+	}
+	kept := []Identifier{}
+	for _, identifier := range identifiers {
+		if !hidden[identifier.Authority] {
+			kept = append(kept, identifier)
+		}
+	}
+	return kept
+}
+
+// transformIdentifiers splits the concept identifiers into alternate UUIDs, which are the UPP authority
+// identifiers other than the canonical UUID, and identifiers from every other authority
+func transformIdentifiers(concept ConceptApiResponse) Identifiers {
+	canonical := canonicalUUID(concept.ID)
+	identifiers := Identifiers{
+		Thing: Thing{
+			ID:        convertID(concept.ID),
+			APIURL:    convertApiUrl(concept.ApiURL, "organisations"),
+			PrefLabel: concept.PrefLabel,
+		},
+		CanonicalUUID: canonical,
+	}
+
+	hasLEI := false
+	for _, identifier := range concept.Identifiers {
+		switch identifier.Authority {
+		case uppAuthority:
+			if identifier.IdentifierValue != canonical {
+				identifiers.AlternateUUIDs = append(identifiers.AlternateUUIDs, identifier.IdentifierValue)
+			}
+		case leiAuthority:
+			hasLEI = true
+			identifiers.Identifiers = append(identifiers.Identifiers, identifier)
+		default:
+			identifiers.Identifiers = append(identifiers.Identifiers, identifier)
+		}
+	}
+	if !hasLEI && concept.LeiCode != "" {
+		identifiers.Identifiers = append(identifiers.Identifiers, Identifier{Authority: leiAuthority, IdentifierValue: concept.LeiCode})
+	}
+	return identifiers
+}
@@ -0,0 +1,128 @@
+package organisations
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetIdentifiers(t *testing.T) {
+	log := logger.NewUPPInfoLogger("tests")
+	var mockClient mockHTTPClient
+
+	testCases := []testCase{
+		{
+			"Get identifiers - Invalid UUID results in error",
+			"/organisations/1234/identifiers",
+			200,
+			getOrganisationWithIdentifiersAsConcept,
+			nil,
+			400,
+			`{"type": "about:blank", "title": "Bad Request", "status": 400, "detail": "uuid '1234' is either missing or invalid", "code": "invalid-uuid", "transactionId": "tid_test"}`,
+		},
+		{
+			"Get identifiers - not found",
+			"/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da/identifiers",
+			404,
+			"",
+			nil,
+			404,
+			`{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "organisation not found", "code": "not-found", "transactionId": "tid_test"}`,
+		},
+		{
+			"Get identifiers - Other type returns not found",
+			"/organisations/f92a4ca4-84f9-11e8-8f42-da24cd01f044/identifiers",
+			200,
+			getPersonAsConcept,
+			nil,
+			404,
+			`{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "organisation not found", "code": "not-found", "transactionId": "tid_test"}`,
+		},
+		{
+			"Get identifiers - Given UUID was not canonical",
+			"/organisations/0d3c2bd1-1a3f-3a0c-9f1e-76c9f5b5e4a1/identifiers",
+			200,
+			getOrganisationWithIdentifiersAsConcept,
+			nil,
+			301,
+			``,
+		},
+		{
+			"Get identifiers - Retrieves and transforms correctly",
+			"/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da/identifiers",
+			200,
+			getOrganisationWithIdentifiersAsConcept,
+			nil,
+			200,
+			getTransformedIdentifiers,
+		},
+	}
+
+	for _, test := range testCases {
+		mockClient.resp = test.clientBody
+		mockClient.statusCode = test.clientCode
+		mockClient.err = test.clientError
+		router := mux.NewRouter()
+		bh := NewHandler(&mockClient, "localhost:8080/concepts", log)
+		bh.RegisterHandlers(router)
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", test.url, nil)
+		req.Header.Set("X-Request-Id", "tid_test")
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		if rr.Code == 200 {
+			assert.Equal(t, transformBody(test.expectedBody), rr.Body.String(), test.name+" failed: status body does not match!")
+			continue
+		}
+		if rr.Code == 301 {
+			assert.Equal(t, "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da/identifiers", rr.Header().Get("Location"), test.name+" failed: redirect does not match!")
+			continue
+		}
+		assert.Equal(t, problemMediaType, rr.Header().Get("Content-Type"), test.name+" failed: content type does not match!")
+		assert.JSONEq(t, test.expectedBody, rr.Body.String(), test.name+" failed: status body does not match!")
+	}
+}
+
+var getOrganisationWithIdentifiersAsConcept = `{
+	"id": "http://www.ft.com/thing/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+	"apiUrl": "http://api.ft.com/concepts/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+	"type": "http://www.ft.com/ontology/organisation/Organisation",
+	"prefLabel": "Nintendo Co Ltd",
+	"leiCode": "353800FEEXU6I9M0ZF27",
+	"identifiers": [
+		{
+			"authority": "http://api.ft.com/system/UPP",
+			"identifierValue": "7c5218a0-3755-463e-abbc-1a1632cfd1da"
+		},
+		{
+			"authority": "http://api.ft.com/system/UPP",
+			"identifierValue": "0d3c2bd1-1a3f-3a0c-9f1e-76c9f5b5e4a1"
+		},
+		{
+			"authority": "http://api.ft.com/system/FACTSET",
+			"identifierValue": "000C7F-E"
+		},
+		{
+			"authority": "http://api.ft.com/system/FT-TME",
+			"identifierValue": "TnN0ZWluX09OX0ZvcnR1bmVDb21wYW55X05UTw==-T04="
+		}
+	]
+}`
+
+var getTransformedIdentifiers = `{
+	"id":"http://api.ft.com/things/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+	"apiUrl":"http://api.ft.com/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+	"prefLabel":"Nintendo Co Ltd",
+	"canonicalUUID":"7c5218a0-3755-463e-abbc-1a1632cfd1da",
+	"alternateUUIDs":["0d3c2bd1-1a3f-3a0c-9f1e-76c9f5b5e4a1"],
+	"identifiers":[
+		{"authority":"http://api.ft.com/system/FACTSET","identifierValue":"000C7F-E"},
+		{"authority":"http://api.ft.com/system/FT-TME","identifierValue":"TnN0ZWluX09OX0ZvcnR1bmVDb21wYW55X05UTw==-T04="},
+		{"authority":"http://api.ft.com/system/LEI","identifierValue":"353800FEEXU6I9M0ZF27"}
+	]
+}`
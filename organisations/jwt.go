@@ -0,0 +1,202 @@
+package organisations
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtLeeway is the clock skew allowed when checking the expiry and not before times of a token
+const jwtLeeway = 30 * time.Second
+
+// jwtAlgorithms are the JWS algorithms tokens may be signed with. none and the HMAC algorithms are left out, as the
+// keys are public.
+var jwtAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// JSONWebKey is a public key of a JSON Web Key Set, RSA and EC keys are supported
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+
+	key crypto.PublicKey
+}
+
+// JSONWebKeySet holds the keys JWTs are verified with
+type JSONWebKeySet struct {
+	Keys []*JSONWebKey `json:"keys"`
+}
+
+// LoadJSONWebKeySet reads the JSON Web Key Set at path. Keys for encryption rather than signatures are left out.
+func LoadJSONWebKeySet(path string) (*JSONWebKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading JWKS file: %w", err)
+	}
+	return ParseJSONWebKeySet(data)
+}
+
+// ParseJSONWebKeySet reads a JSON Web Key Set
+func ParseJSONWebKeySet(data []byte) (*JSONWebKeySet, error) {
+	var set JSONWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parsing JWKS: %w", err)
+	}
+	keys := set.Keys[:0]
+	for i, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if err := key.parse(); err != nil {
+			return nil, fmt.Errorf("JWKS key %d %s: %w", i, key.KeyID, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no signature keys")
+	}
+	set.Keys = keys
+	return &set, nil
+}
+
+func (k *JSONWebKey) parse() error {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return fmt.Errorf("exponent is invalid")
+		}
+		k.key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return fmt.Errorf("curve %s is not supported", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return errors.New("point is not on the curve")
+		}
+		k.key = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	default:
+		return fmt.Errorf("key type %s is not supported", k.KeyType)
+	}
+	return nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("is not a base64url encoded integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// key finds the key a token with the key ID was signed with. Tokens without a key ID need a set of a single key.
+func (s *JSONWebKeySet) key(keyID string, algorithm string) (*JSONWebKey, error) {
+	var found *JSONWebKey
+	switch {
+	case keyID != "":
+		for _, key := range s.Keys {
+			if key.KeyID == keyID {
+				found = key
+				break
+			}
+		}
+	case len(s.Keys) == 1:
+		found = s.Keys[0]
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no key matches key ID '%s'", keyID)
+	}
+	if found.Algorithm != "" && found.Algorithm != algorithm {
+		return nil, fmt.Errorf("key %s is for %s, not %s", found.KeyID, found.Algorithm, algorithm)
+	}
+	return found, nil
+}
+
+// jwtClaims are the registered claims checked when verifying a token, with the scopes it grants
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Scope  string          `json:"scope"`
+	Scopes json.RawMessage `json:"scp"`
+}
+
+// scopes lists the scopes of the space separated scope claim, or of the scp claim as a list or a string
+func (c jwtClaims) scopes() []string {
+	scopes := strings.Fields(c.Scope)
+	var list []string
+	var single string
+	switch {
+	case json.Unmarshal(c.Scopes, &list) == nil:
+		scopes = append(scopes, list...)
+	case json.Unmarshal(c.Scopes, &single) == nil:
+		scopes = append(scopes, strings.Fields(single)...)
+	}
+	return scopes
+}
+
+// verifyJWT checks the signature of a compact serialised JWT with the key set, and that it is current. The issuer
+// and audience are checked when they are not empty.
+func verifyJWT(token string, keys *JSONWebKeySet, issuer string, audience string, now time.Time) (jwtClaims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtAlgorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	}
+	if issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+
+	var claims jwtClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		key, err := keys.key(keyID, token.Method.Alg())
+		if err != nil {
+			return nil, err
+		}
+		return key.key, nil
+	}, opts...)
+	if err != nil {
+		return jwtClaims{}, err
+	}
+	return claims, nil
+}
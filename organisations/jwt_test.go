@@ -0,0 +1,191 @@
+package organisations
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSigner signs JWTs with the keys of a test JWKS
+type testSigner struct {
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	jwks   []byte
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	encode := func(b []byte) string {
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	jwks, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "alg": "RS256", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X.FillBytes(make([]byte, 32))), "y": encode(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "RSA", "kid": "encryption", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}})
+	require.NoError(t, err)
+	return &testSigner{rsaKey: rsaKey, ecKey: ecKey, jwks: jwks}
+}
+
+// sign signs the claims with the algorithm of the header. HMAC tokens are signed with the public RSA key as the
+// secret, as an attacker confusing the algorithms would.
+func (s *testSigner) sign(t *testing.T, header map[string]string, claims map[string]interface{}) string {
+	alg := header["alg"]
+	var key interface{}
+	switch {
+	case alg == "none":
+		key = jwt.UnsafeAllowNoneSignatureType
+	case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "PS"):
+		key = s.rsaKey
+	case strings.HasPrefix(alg, "ES"):
+		key = s.ecKey
+	case strings.HasPrefix(alg, "HS"):
+		key = pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&s.rsaKey.PublicKey)})
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(alg), jwt.MapClaims(claims))
+	delete(token.Header, "typ")
+	for name, value := range header {
+		token.Header[name] = value
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+// resign replaces the signature of a token
+func resign(token string, signature func([]byte) []byte) string {
+	i := strings.LastIndex(token, ".")
+	sig, _ := base64.RawURLEncoding.DecodeString(token[i+1:])
+	return token[:i+1] + base64.RawURLEncoding.EncodeToString(signature(sig))
+}
+
+func TestVerifyJWT(t *testing.T) {
+	signer := newTestSigner(t)
+	keys, err := ParseJSONWebKeySet(signer.jwks)
+	require.NoError(t, err)
+	assert.Len(t, keys.Keys, 2, "encryption keys are left out")
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{"sub": "next-app", "iss": "https://auth.ft.com", "aud": []string{"organisations"}, "exp": now.Add(time.Hour).Unix()}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+	rs256 := map[string]string{"alg": "RS256", "kid": "rsa"}
+	es256 := map[string]string{"alg": "ES256", "kid": "ec"}
+
+	verified, err := verifyJWT(signer.sign(t, rs256, claims(map[string]interface{}{"scope": "organisations:lei other"})),
+		keys, "https://auth.ft.com", "organisations", now)
+	require.NoError(t, err)
+	assert.Equal(t, "next-app", verified.Subject)
+	assert.Equal(t, []string{"organisations:lei", "other"}, verified.scopes())
+
+	verified, err = verifyJWT(signer.sign(t, es256, claims(map[string]interface{}{"scp": []string{"organisations:lei"}, "aud": "organisations"})),
+		keys, "", "organisations", now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"organisations:lei"}, verified.scopes())
+
+	for _, test := range []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"expired within the leeway", signer.sign(t, rs256, claims(map[string]interface{}{"exp": now.Add(-10 * time.Second).Unix()})), true},
+		{"not yet valid within the leeway", signer.sign(t, rs256, claims(map[string]interface{}{"nbf": now.Add(10 * time.Second).Unix()})), true},
+		{"expired", signer.sign(t, rs256, claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()})), false},
+		{"not yet valid", signer.sign(t, rs256, claims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()})), false},
+		{"no expiry", signer.sign(t, rs256, map[string]interface{}{"sub": "next-app", "iss": "https://auth.ft.com", "aud": "organisations"}), false},
+		{"expiry is not a number", signer.sign(t, rs256, claims(map[string]interface{}{"exp": "tomorrow"})), false},
+		{"wrong issuer", signer.sign(t, rs256, claims(map[string]interface{}{"iss": "https://evil.example.com"})), false},
+		{"no issuer", signer.sign(t, rs256, claims(map[string]interface{}{"iss": nil})), false},
+		{"wrong audience", signer.sign(t, rs256, claims(map[string]interface{}{"aud": "people"})), false},
+		{"wrong audience in a list", signer.sign(t, rs256, claims(map[string]interface{}{"aud": []string{"people", "content"}})), false},
+		{"unknown key id", signer.sign(t, map[string]string{"alg": "RS256", "kid": "other"}, claims(nil)), false},
+		{"no key id with several keys", signer.sign(t, map[string]string{"alg": "RS256"}, claims(nil)), false},
+		{"key id of another key", signer.sign(t, map[string]string{"alg": "RS256", "kid": "ec"}, claims(nil)), false},
+		{"algorithm the key is not for", signer.sign(t, map[string]string{"alg": "ES256", "kid": "rsa"}, claims(nil)), false},
+		{"PS256 with an RS256 key", signer.sign(t, map[string]string{"alg": "PS256", "kid": "rsa"}, claims(nil)), false},
+		{"none", signer.sign(t, map[string]string{"alg": "none", "kid": "rsa"}, claims(nil)), false},
+		{"none without a key id", signer.sign(t, map[string]string{"alg": "none"}, claims(nil)), false},
+		{"HS256 signed with the RSA public key", signer.sign(t, map[string]string{"alg": "HS256", "kid": "rsa"}, claims(nil)), false},
+		{"HS256 signed with the RSA public key without an alg on the key", signer.sign(t, map[string]string{"alg": "HS256", "kid": "ec"}, claims(nil)), false},
+		{"tampered RSA signature", resign(signer.sign(t, rs256, claims(nil)), func(sig []byte) []byte { sig[0] ^= 0xff; return sig }), false},
+		{"ES signature too short", resign(signer.sign(t, es256, claims(nil)), func(sig []byte) []byte { return sig[:63] }), false},
+		{"ES signature too long", resign(signer.sign(t, es256, claims(nil)), func(sig []byte) []byte { return append(sig, 0) }), false},
+		{"ES signature in ASN.1", resign(signer.sign(t, es256, claims(nil)), func(sig []byte) []byte {
+			r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+			der, _ := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+			return der
+		}), false},
+		{"empty signature", resign(signer.sign(t, es256, claims(nil)), func([]byte) []byte { return nil }), false},
+		{"one segment", "nintendo", false},
+		{"two segments", "eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJuZXh0LWFwcCJ9", false},
+		{"four segments", signer.sign(t, rs256, claims(nil)) + ".AAAA", false},
+		{"header is not base64", "!!!." + strings.SplitN(signer.sign(t, rs256, claims(nil)), ".", 2)[1], false},
+		{"claims are not JSON", "eyJhbGciOiJSUzI1NiIsImtpZCI6InJzYSJ9.bmludGVuZG8.AAAA", false},
+		{"empty", "", false},
+	} {
+		_, err := verifyJWT(test.token, keys, "https://auth.ft.com", "organisations", now)
+		if test.valid {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.Error(t, err, test.name)
+		}
+	}
+
+	_, err = verifyJWT(signer.sign(t, rs256, claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()})), keys, "", "", now)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	_, err = verifyJWT(resign(signer.sign(t, rs256, claims(nil)), func(sig []byte) []byte { sig[0] ^= 0xff; return sig }), keys, "", "", now)
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+	_, err = verifyJWT("nintendo", keys, "", "", now)
+	assert.ErrorIs(t, err, jwt.ErrTokenMalformed)
+}
+
+func TestVerifyJWTWithASingleKey(t *testing.T) {
+	signer := newTestSigner(t)
+	var set map[string][]json.RawMessage
+	require.NoError(t, json.Unmarshal(signer.jwks, &set))
+	keys, err := ParseJSONWebKeySet([]byte(`{"keys": [` + string(set["keys"][1]) + `]}`))
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	claims := map[string]interface{}{"sub": "next-app", "exp": now.Add(time.Hour).Unix()}
+
+	_, err = verifyJWT(signer.sign(t, map[string]string{"alg": "ES256"}, claims), keys, "", "", now)
+	assert.NoError(t, err, "tokens without a key id are verified with the only key")
+	_, err = verifyJWT(signer.sign(t, map[string]string{"alg": "HS256"}, claims), keys, "", "", now)
+	assert.Error(t, err)
+	_, err = verifyJWT(signer.sign(t, map[string]string{"alg": "none"}, claims), keys, "", "", now)
+	assert.Error(t, err)
+}
+
+func TestParseJSONWebKeySetRejectsInvalidKeys(t *testing.T) {
+	for _, jwks := range []string{
+		`{"keys": []}`,
+		`{"keys": [{"kty": "oct", "k": "c2VjcmV0"}]}`,
+		`{"keys": [{"kty": "EC", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`,
+		`{"keys": [{"kty": "RSA", "n": "", "e": "AQAB"}]}`,
+		`nintendo`,
+	} {
+		_, err := ParseJSONWebKeySet([]byte(jwks))
+		assert.Error(t, err, jwks)
+	}
+}
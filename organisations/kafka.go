@@ -0,0 +1,130 @@
+package organisations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaSource is a MessageSource reading every partition of a Kafka topic from its latest message, without a
+// consumer group. Every instance caches organisations itself, so every instance reads every change, and as its cache
+// starts empty it has no use for changes made before it started: there are no offsets to commit, and no groups are
+// left behind on the brokers by instances that have gone.
+type KafkaSource struct {
+	readers  []*kafka.Reader
+	messages chan kafkaResult
+	cancel   context.CancelFunc
+}
+
+type kafkaResult struct {
+	message kafka.Message
+	err     error
+}
+
+// NewKafkaSource reads the partitions of topic from brokers. The partitions are listed once, partitions added to
+// the topic later are only read after a restart.
+func NewKafkaSource(brokers []string, topic string) (*KafkaSource, error) {
+	partitions, err := topicPartitions(brokers, topic)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &KafkaSource{messages: make(chan kafkaResult), cancel: cancel}
+	for _, partition := range partitions {
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   brokers,
+			Topic:     topic,
+			Partition: partition.ID,
+			MinBytes:  1,
+			MaxBytes:  10e6,
+		})
+		if err := reader.SetOffset(kafka.LastOffset); err != nil {
+			s.Close()
+			reader.Close()
+			return nil, fmt.Errorf("starting to read partition %d of %s: %w", partition.ID, topic, err)
+		}
+		s.readers = append(s.readers, reader)
+		go s.read(ctx, reader)
+	}
+	return s, nil
+}
+
+func topicPartitions(brokers []string, topic string) ([]kafka.Partition, error) {
+	var errs []error
+	for _, broker := range brokers {
+		conn, err := kafka.Dial("tcp", broker)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		partitions, err := conn.ReadPartitions(topic)
+		conn.Close()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return partitions, nil
+	}
+	return nil, fmt.Errorf("listing the partitions of %s: %w", topic, errors.Join(errs...))
+}
+
+// read passes on the messages of one partition until ctx is done
+func (s *KafkaSource) read(ctx context.Context, reader *kafka.Reader) {
+	for {
+		m, err := reader.ReadMessage(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case s.messages <- kafkaResult{message: m, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			select {
+			case <-time.After(consumeRetryDelay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (s *KafkaSource) Next(ctx context.Context) (Message, error) {
+	var result kafkaResult
+	select {
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	case result = <-s.messages:
+	}
+	if result.err != nil {
+		return Message{}, result.err
+	}
+	m := result.message
+	transID, body := parseFTMessage(m.Value)
+	for _, header := range m.Headers {
+		if transID == "" && strings.EqualFold(header.Key, transactionidutils.TransactionIDHeader) {
+			transID = string(header.Value)
+		}
+	}
+	return Message{Body: body, TransactionID: transID, offset: m.Offset}, nil
+}
+
+// Commit does nothing, as the partitions are read without a consumer group
+func (s *KafkaSource) Commit(ctx context.Context, msg Message) error {
+	return nil
+}
+
+func (s *KafkaSource) Close() error {
+	s.cancel()
+	var errs []error
+	for _, reader := range s.readers {
+		errs = append(errs, reader.Close())
+	}
+	return errors.Join(errs...)
+}
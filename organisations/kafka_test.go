@@ -0,0 +1,61 @@
+package organisations
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestKafkaSourcesOfTwoInstancesBothSeeAnEvent needs a broker, set KAFKA_BROKERS to a comma separated list to run it
+func TestKafkaSourcesOfTwoInstancesBothSeeAnEvent(t *testing.T) {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	if brokers == "" {
+		t.Skip("KAFKA_BROKERS is not set")
+	}
+	topic := "public-organisations-api-test-" + time.Now().Format("20060102150405.000000")
+	conn, err := kafka.Dial("tcp", strings.Split(brokers, ",")[0])
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.CreateTopics(kafka.TopicConfig{Topic: topic, NumPartitions: 2, ReplicationFactor: 1}))
+	defer conn.DeleteTopics(topic)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var sources []*KafkaSource
+	for i := 0; i < 2; i++ {
+		source, err := NewKafkaSource(strings.Split(brokers, ","), topic)
+		require.NoError(t, err)
+		sources = append(sources, source)
+	}
+	received := make(chan Message, len(sources))
+	for _, source := range sources {
+		defer source.Close()
+		go func(source *KafkaSource) {
+			if msg, err := source.Next(ctx); err == nil {
+				received <- msg
+			}
+		}(source)
+	}
+
+	writer := &kafka.Writer{Addr: kafka.TCP(strings.Split(brokers, ",")...), Topic: topic}
+	defer writer.Close()
+	event := `{"type": "PublicCompany", "uuid": "7c5218a0-3755-463e-abbc-1a1632cfd1da"}`
+	// the sources start at the latest change once they have connected, so keep publishing until both saw the event
+	for seen := 0; seen < len(sources); {
+		require.NoError(t, writer.WriteMessages(ctx, kafka.Message{Value: []byte(event)}))
+		select {
+		case msg := <-received:
+			assert.JSONEq(t, event, string(msg.Body))
+			seen++
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			t.Fatalf("only %d of %d instances saw the event", seen, len(sources))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package organisations
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// showLabelDetailsParam opts in to the typed labelDetails of an organisation
+	showLabelDetailsParam = "showLabelDetails"
+	skosXLPrefix          = "http://www.w3.org/2008/05/skos-xl#"
+	skosCorePrefix        = "http://www.w3.org/2004/02/skos/core#"
+)
+
+// labelTypes maps the label types used by public-concepts-api to the names exposed in labelDetails.
+// Hidden labels and aliases keep their own names so they can be told apart from display names.
+var labelTypes = map[string]string{
+	ontologyPrefix + "/properName":  "properName",
+	ontologyPrefix + "/shortName":   "shortName",
+	ontologyPrefix + "/formerName":  "formerName",
+	ontologyPrefix + "/Alias":       "alias",
+	ontologyPrefix + "/hiddenLabel": "hiddenLabel",
+	skosXLPrefix + "altLabel":       "alternativeLabel",
+	skosXLPrefix + "hiddenLabel":    "hiddenLabel",
+	skosCorePrefix + "altLabel":     "alternativeLabel",
+	skosCorePrefix + "hiddenLabel":  "hiddenLabel",
+}
+
+// normaliseLabelType returns the labelDetails name for a label type, known is false for types outside of
+// labelTypes, which are named after the last segment of their URI
+func normaliseLabelType(labelType string) (name string, known bool) {
+	if name, ok := labelTypes[labelType]; ok {
+		return name, true
+	}
+	name = labelType
+	if i := strings.LastIndexAny(name, "/#"); i >= 0 {
+		name = name[i+1:]
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:], false
+}
+
+func transformLabelDetails(labels []TypedValue) []LabelDetail {
+	details := []LabelDetail{}
+	for _, label := range labels {
+		name, _ := normaliseLabelType(label.Type)
+		details = append(details, LabelDetail{Type: name, Value: label.Value})
+	}
+	return details
+}
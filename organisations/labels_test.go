@@ -0,0 +1,83 @@
+package organisations
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormaliseLabelType(t *testing.T) {
+	testCases := []struct {
+		labelType    string
+		expectedName string
+		known        bool
+	}{
+		{"http://www.ft.com/ontology/properName", "properName", true},
+		{"http://www.ft.com/ontology/Alias", "alias", true},
+		{"http://www.w3.org/2008/05/skos-xl#altLabel", "alternativeLabel", true},
+		{"http://www.w3.org/2008/05/skos-xl#hiddenLabel", "hiddenLabel", true},
+		{"http://www.ft.com/ontology/TradeName", "tradeName", false},
+	}
+
+	for _, tc := range testCases {
+		name, known := normaliseLabelType(tc.labelType)
+		assert.Equal(t, tc.expectedName, name, tc.labelType)
+		assert.Equal(t, tc.known, known, tc.labelType)
+	}
+}
+
+func TestLabelDetailsAreOptIn(t *testing.T) {
+	log := logger.NewUPPInfoLogger("tests")
+	mockClient := mockHTTPClient{resp: getOrganisationWithHiddenLabelsAsConcept, statusCode: 200}
+	router := mux.NewRouter()
+	bh := NewHandler(&mockClient, "localhost:8080/concepts", log)
+	bh.RegisterHandlers(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", nil))
+	assert.Equal(t, 200, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "labelDetails")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da?showLabelDetails=true", nil))
+	assert.Equal(t, 200, rec.Code)
+
+	org := Organisation{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &org))
+	assert.Equal(t, []LabelDetail{
+		{Type: "properName", Value: "Nintendo Co., Ltd."},
+		{Type: "alias", Value: "Nintendo"},
+		{Type: "hiddenLabel", Value: "Nintendo KK"},
+		{Type: "alternativeLabel", Value: "Nintendo"},
+	}, org.LabelDetails)
+	assert.Equal(t, []string{"Nintendo Co., Ltd.", "Nintendo", "Nintendo KK"}, org.Labels)
+}
+
+var getOrganisationWithHiddenLabelsAsConcept = `{
+	"id": "http://www.ft.com/thing/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+	"apiUrl": "http://api.ft.com/concepts/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+	"type": "http://www.ft.com/ontology/organisation/Organisation",
+	"prefLabel": "Nintendo Co Ltd",
+	"alternativeLabels": [
+		{
+			"type": "http://www.ft.com/ontology/properName",
+			"value": "Nintendo Co., Ltd."
+		},
+		{
+			"type": "http://www.ft.com/ontology/Alias",
+			"value": "Nintendo"
+		},
+		{
+			"type": "http://www.w3.org/2008/05/skos-xl#hiddenLabel",
+			"value": "Nintendo KK"
+		},
+		{
+			"type": "http://www.w3.org/2008/05/skos-xl#altLabel",
+			"value": "Nintendo"
+		}
+	]
+}`
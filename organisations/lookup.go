@@ -0,0 +1,137 @@
+package organisations
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+const (
+	authorityPrefix     = "http://api.ft.com/system/"
+	maxIdentifierValues = 100
+)
+
+// authorityAliases maps the short authority names clients use to the authority names known to UPP
+var authorityAliases = map[string]string{
+	"factset": "FACTSET",
+	"tme":     "FT-TME",
+	"ft-tme":  "FT-TME",
+	"lei":     "LEI",
+	"upp":     "UPP",
+}
+
+// LookupOrganisations resolves authority identifiers to organisations. A single identifier is redirected to
+// the canonical organisation, several identifiers are answered with the concordances that were found. A single
+// identifier of several organisations is answered with 300 Multiple Choices and their concordances, rather than
+// redirecting to one of them.
+func (h *OrganisationsHandler) LookupOrganisations(w http.ResponseWriter, r *http.Request) {
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+	query := r.URL.Query()
+	authority := query.Get("authority")
+	values := identifierValues(query["identifierValue"])
+
+	if authority == "" || len(values) == 0 {
+		writeProblem(w, r, http.StatusBadRequest, errorInvalidParameters, "authority and identifierValue query parameters are required")
+		return
+	}
+	if len(values) > maxIdentifierValues {
+		writeProblem(w, r, http.StatusBadRequest, errorInvalidParameters, "too many identifierValue query parameters")
+		return
+	}
+
+	// Callers not entitled to an authority's identifiers cannot learn which organisations they belong to either
+	concordances := []Concordance{}
+	if !h.hiddenAuthorities(w, r)[normaliseAuthority(authority)] {
+		var err error
+		if concordances, err = h.lookupFromSource(r.Context(), normaliseAuthority(authority), values, transID); err != nil {
+			writeUpstreamProblem(w, r, "failed to look up organisations", err)
+			return
+		}
+	}
+
+	status := http.StatusOK
+	if len(values) == 1 {
+		switch len(concordances) {
+		case 0:
+			writeProblem(w, r, http.StatusNotFound, errorNotFound, "organisation not found")
+			return
+		case 1:
+			w.Header().Set("Location", "/organisations/"+canonicalUUID(concordances[0].Organisation.ID))
+			w.WriteHeader(http.StatusMovedPermanently)
+			return
+		}
+		status = http.StatusMultipleChoices
+	}
+
+	w.Header().Set("Cache-Control", cacheControl())
+	if err := writeEncoded(w, JSONFormat, status, Concordances{Concordances: concordances}); err != nil {
+		h.logger.WithTransactionID(transID).WithError(err).Error("failed to encode concordances")
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "concordances could not be encoded")
+	}
+}
+
+func (h *OrganisationsHandler) lookupFromSource(ctx context.Context, authority string, values []string, transID string) ([]Concordance, error) {
+	concepts, err := h.source.Search(ctx, authority, values, transID)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := make(map[string]bool)
+	for _, v := range values {
+		requested[v] = true
+	}
+
+	concordances := []Concordance{}
+	for _, concept := range concepts {
+		if _, isOrganisation := h.organisationTypes(concept, transID); !isOrganisation {
+			continue
+		}
+		organisation := Thing{
+			ID:        convertID(concept.ID),
+			APIURL:    convertApiUrl(concept.ApiURL, "organisations"),
+			PrefLabel: concept.PrefLabel,
+		}
+		identifiers := concept.Identifiers
+		if concept.LeiCode != "" {
+			identifiers = append(identifiers[:len(identifiers):len(identifiers)], Identifier{Authority: leiAuthority, IdentifierValue: concept.LeiCode})
+		}
+		matched := make(map[string]bool)
+		for _, identifier := range identifiers {
+			if identifier.Authority != authority || !requested[identifier.IdentifierValue] || matched[identifier.IdentifierValue] {
+				continue
+			}
+			matched[identifier.IdentifierValue] = true
+			concordances = append(concordances, Concordance{Identifier: identifier, Organisation: organisation})
+		}
+	}
+	return concordances, nil
+}
+
+// identifierValues accepts both repeated and comma separated identifierValue parameters
+func identifierValues(params []string) []string {
+	values := []string{}
+	seen := make(map[string]bool)
+	for _, param := range params {
+		for _, v := range strings.Split(param, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" || seen[v] {
+				continue
+			}
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func normaliseAuthority(authority string) string {
+	if strings.HasPrefix(authority, "http://") || strings.HasPrefix(authority, "https://") {
+		return authority
+	}
+	if alias, ok := authorityAliases[strings.ToLower(authority)]; ok {
+		return authorityPrefix + alias
+	}
+	return authorityPrefix + strings.ToUpper(authority)
+}
@@ -0,0 +1,197 @@
+package organisations
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupOrganisations(t *testing.T) {
+	log := logger.NewUPPInfoLogger("tests")
+	var mockClient mockHTTPClient
+
+	testCases := []testCase{
+		{
+			"Lookup - Missing authority results in error",
+			"/organisations?identifierValue=000C7F-E",
+			200,
+			getSearchResultAsConcepts,
+			nil,
+			400,
+			`{"type": "about:blank", "title": "Bad Request", "status": 400, "detail": "authority and identifierValue query parameters are required", "code": "invalid-parameters", "transactionId": "tid_test"}`,
+		},
+		{
+			"Lookup - Concepts API error results in error",
+			"/organisations?authority=FACTSET&identifierValue=000C7F-E",
+			503,
+			"",
+			errors.New("Downstream error"),
+			500,
+			`{"type": "about:blank", "title": "Internal Server Error", "status": 500, "detail": "failed to look up organisations", "code": "upstream-error", "transactionId": "tid_test"}`,
+		},
+		{
+			"Lookup - Unknown identifier returns not found",
+			"/organisations?authority=FACTSET&identifierValue=XXXXXX-E",
+			200,
+			getSearchResultAsConcepts,
+			nil,
+			404,
+			`{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "organisation not found", "code": "not-found", "transactionId": "tid_test"}`,
+		},
+		{
+			"Lookup - Single identifier redirects to the canonical organisation",
+			"/organisations?authority=factset&identifierValue=000C7F-E",
+			200,
+			getSearchResultAsConcepts,
+			nil,
+			301,
+			``,
+		},
+		{
+			"Lookup - Single identifier of several organisations returns their concordances",
+			"/organisations?authority=FACTSET&identifierValue=000C7F-E",
+			200,
+			getAmbiguousSearchResultAsConcepts,
+			nil,
+			300,
+			getAmbiguousLookupConcordances,
+		},
+		{
+			"Lookup - Several identifiers return concordances for organisations only",
+			"/organisations?authority=FACTSET&identifierValue=000C7F-E,05N3DN-E&identifierValue=XXXXXX-E",
+			200,
+			getSearchResultAsConcepts,
+			nil,
+			200,
+			getLookupConcordances,
+		},
+	}
+
+	for _, test := range testCases {
+		mockClient.resp = test.clientBody
+		mockClient.statusCode = test.clientCode
+		mockClient.err = test.clientError
+		router := mux.NewRouter()
+		bh := NewHandler(&mockClient, "localhost:8080/concepts", log)
+		bh.RegisterHandlers(router)
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", test.url, nil)
+		req.Header.Set("X-Request-Id", "tid_test")
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		switch rr.Code {
+		case 200, 300:
+			assert.Equal(t, transformBody(test.expectedBody), rr.Body.String(), test.name+" failed: status body does not match!")
+		case 301:
+			assert.Equal(t, "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", rr.Header().Get("Location"), test.name+" failed: redirect does not match!")
+		default:
+			assert.Equal(t, problemMediaType, rr.Header().Get("Content-Type"), test.name+" failed: content type does not match!")
+			assert.JSONEq(t, test.expectedBody, rr.Body.String(), test.name+" failed: status body does not match!")
+		}
+	}
+}
+
+func TestNormaliseAuthority(t *testing.T) {
+	assert.Equal(t, "http://api.ft.com/system/FACTSET", normaliseAuthority("factset"))
+	assert.Equal(t, "http://api.ft.com/system/FT-TME", normaliseAuthority("TME"))
+	assert.Equal(t, "http://api.ft.com/system/WIKIDATA", normaliseAuthority("wikidata"))
+	assert.Equal(t, "http://api.ft.com/system/FT-TME", normaliseAuthority("http://api.ft.com/system/FT-TME"))
+}
+
+var getSearchResultAsConcepts = `{
+	"concepts": [
+		{
+			"id": "http://www.ft.com/thing/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+			"apiUrl": "http://api.ft.com/concepts/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+			"type": "http://www.ft.com/ontology/company/PublicCompany",
+			"prefLabel": "Nintendo Co Ltd",
+			"identifiers": [
+				{
+					"authority": "http://api.ft.com/system/FACTSET",
+					"identifierValue": "000C7F-E"
+				}
+			]
+		},
+		{
+			"id": "http://www.ft.com/thing/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+			"apiUrl": "http://api.ft.com/concepts/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+			"type": "http://www.ft.com/ontology/person/Person",
+			"prefLabel": "Not a organisation",
+			"identifiers": [
+				{
+					"authority": "http://api.ft.com/system/FACTSET",
+					"identifierValue": "05N3DN-E"
+				}
+			]
+		}
+	]
+}`
+
+var getLookupConcordances = `{
+	"concordances":[
+		{
+			"identifier":{"authority":"http://api.ft.com/system/FACTSET","identifierValue":"000C7F-E"},
+			"organisation":{
+				"id":"http://api.ft.com/things/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+				"apiUrl":"http://api.ft.com/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+				"prefLabel":"Nintendo Co Ltd"
+			}
+		}
+	]
+}`
+
+var getAmbiguousSearchResultAsConcepts = `{
+	"concepts": [
+		{
+			"id": "http://www.ft.com/thing/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+			"apiUrl": "http://api.ft.com/concepts/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+			"type": "http://www.ft.com/ontology/company/PublicCompany",
+			"prefLabel": "Nintendo Co Ltd",
+			"identifiers": [
+				{
+					"authority": "http://api.ft.com/system/FACTSET",
+					"identifierValue": "000C7F-E"
+				}
+			]
+		},
+		{
+			"id": "http://www.ft.com/thing/3fa9d5a4-5b3b-4c1e-9f0c-2b8f2f0a2d8e",
+			"apiUrl": "http://api.ft.com/concepts/3fa9d5a4-5b3b-4c1e-9f0c-2b8f2f0a2d8e",
+			"type": "http://www.ft.com/ontology/company/PublicCompany",
+			"prefLabel": "Nintendo of America Inc",
+			"identifiers": [
+				{
+					"authority": "http://api.ft.com/system/FACTSET",
+					"identifierValue": "000C7F-E"
+				}
+			]
+		}
+	]
+}`
+
+var getAmbiguousLookupConcordances = `{
+	"concordances":[
+		{
+			"identifier":{"authority":"http://api.ft.com/system/FACTSET","identifierValue":"000C7F-E"},
+			"organisation":{
+				"id":"http://api.ft.com/things/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+				"apiUrl":"http://api.ft.com/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+				"prefLabel":"Nintendo Co Ltd"
+			}
+		},
+		{
+			"identifier":{"authority":"http://api.ft.com/system/FACTSET","identifierValue":"000C7F-E"},
+			"organisation":{
+				"id":"http://api.ft.com/things/3fa9d5a4-5b3b-4c1e-9f0c-2b8f2f0a2d8e",
+				"apiUrl":"http://api.ft.com/organisations/3fa9d5a4-5b3b-4c1e-9f0c-2b8f2f0a2d8e",
+				"prefLabel":"Nintendo of America Inc"
+			}
+		}
+	]
+}`
@@ -0,0 +1,126 @@
+package organisations
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "public_organisations_api"
+
+// lookupOutcome describes how a request for an organisation was answered
+type lookupOutcome string
+
+const (
+	outcomeFound             lookupOutcome = "found"
+	outcomeRedirect          lookupOutcome = "redirect"
+	outcomeNotOrganisation   lookupOutcome = "not-organisation"
+	outcomeNotFound          lookupOutcome = "not-found"
+	outcomeUpstreamError     lookupOutcome = "upstream-error"
+	outcomeInvalidUUID       lookupOutcome = "invalid-uuid"
+	outcomeContractViolation lookupOutcome = "contract-violation"
+	outcomeNotAcceptable     lookupOutcome = "not-acceptable"
+	outcomeEncodingError     lookupOutcome = "encoding-error"
+)
+
+// Metrics are the Prometheus collectors describing how organisations are looked up and transformed
+type Metrics struct {
+	requests           *prometheus.CounterVec
+	conceptsAPILatency *prometheus.HistogramVec
+	transformDuration  prometheus.Histogram
+	unknownPredicates  *prometheus.CounterVec
+	unknownLabelTypes  *prometheus.CounterVec
+	conceptEvents      *prometheus.CounterVec
+	cacheUpdates       *prometheus.CounterVec
+	contractViolations *prometheus.CounterVec
+	versionRequests    *prometheus.CounterVec
+}
+
+// NewMetrics creates the organisation metrics and registers them with registerer
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "organisation_requests_total",
+			Help:      "Requests for an organisation by response status and lookup outcome.",
+		}, []string{"status", "outcome"}),
+		conceptsAPILatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "concepts_api_request_duration_seconds",
+			Help:      "Latency of requests to public-concepts-api by response status, error if no response was received.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"status"}),
+		transformDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "transform_duration_seconds",
+			Help:      "Time taken to transform a concept into an organisation.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 8),
+		}),
+		unknownPredicates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "unknown_predicates_total",
+			Help:      "Related concept predicates ignored while transforming organisations.",
+		}, []string{"predicate"}),
+		unknownLabelTypes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "unknown_label_types_total",
+			Help:      "Alternative label types without a known labelDetails name met while transforming organisations.",
+		}, []string{"type"}),
+		conceptEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "concept_events_total",
+			Help:      "Concept change events consumed by result: applied when cached organisations were affected, ignored when none were, invalid when the event could not be read.",
+		}, []string{"result"}),
+		cacheUpdates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "organisation_cache_updates_total",
+			Help:      "Cached organisations invalidated or refreshed because of concept change events.",
+		}, []string{"action"}),
+		contractViolations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "contract_violations_total",
+			Help:      "Ways organisations did not match the API contract, by JSON pointer of the field with array indexes as * and by broken schema rule.",
+		}, []string{"field", "rule"}),
+		versionRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "api_version_requests_total",
+			Help:      "Requests for the public API by API version and route.",
+		}, []string{"version", "route"}),
+	}
+	registerer.MustRegister(m.requests, m.conceptsAPILatency, m.transformDuration, m.unknownPredicates, m.unknownLabelTypes,
+		m.conceptEvents, m.cacheUpdates, m.contractViolations, m.versionRequests)
+	return m
+}
+
+// WithMetrics records the organisation metrics in m instead of an unexposed registry
+func WithMetrics(m *Metrics) HandlerOption {
+	return func(h *OrganisationsHandler) {
+		h.metrics = m
+	}
+}
+
+func (m *Metrics) observeRequest(status int, outcome lookupOutcome) {
+	m.requests.WithLabelValues(strconv.Itoa(status), string(outcome)).Inc()
+}
+
+func (m *Metrics) observeConceptsAPI(status string, latency time.Duration) {
+	m.conceptsAPILatency.WithLabelValues(status).Observe(latency.Seconds())
+}
+
+// observeUnknowns counts the predicates and label types of the concept the transform does not recognise
+func (m *Metrics) observeUnknowns(concept ConceptApiResponse) {
+	for _, related := range concept.Related {
+		switch strings.TrimPrefix(related.Predicate, ontologyPrefix) {
+		case hasParentPredicate, isParentPredicate, issuedPredicate:
+		default:
+			m.unknownPredicates.WithLabelValues(related.Predicate).Inc()
+		}
+	}
+	for _, label := range concept.AlternativeLabels {
+		if _, known := normaliseLabelType(label.Type); !known {
+			m.unknownLabelTypes.WithLabelValues(label.Type).Inc()
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package organisations
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsRecordOutcomes(t *testing.T) {
+	log := logger.NewUPPInfoLogger("tests")
+	registry := prometheus.NewRegistry()
+	var mockClient mockHTTPClient
+	router := mux.NewRouter()
+	bh := NewHandler(&mockClient, "localhost:8080/concepts", log, WithMetrics(NewMetrics(registry)))
+	bh.RegisterHandlers(router)
+
+	requests := []struct {
+		url        string
+		clientBody string
+		clientCode int
+	}{
+		{"/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", getCompleteOrganisationAsConcept, 200},
+		{"/organisations/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", getRedirectedOrganisation, 200},
+		{"/organisations/f92a4ca4-84f9-11e8-8f42-da24cd01f044", getPersonAsConcept, 200},
+		{"/organisations/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "", 404},
+		{"/organisations/52aa645b-79d6-4f6f-910b-e1cff3f25a15", `{`, 200},
+		{"/organisations/1234", "", 200},
+	}
+	for _, r := range requests {
+		mockClient.resp = r.clientBody
+		mockClient.statusCode = r.clientCode
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", r.url, nil))
+	}
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	metrics := map[string]*dto.MetricFamily{}
+	for _, f := range families {
+		metrics[f.GetName()] = f
+	}
+
+	outcomes := map[string]string{}
+	for _, m := range metrics["public_organisations_api_organisation_requests_total"].GetMetric() {
+		labels := map[string]string{}
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		outcomes[labels["outcome"]] = labels["status"]
+	}
+	assert.Equal(t, map[string]string{
+		"found":            "200",
+		"redirect":         "301",
+		"not-organisation": "404",
+		"not-found":        "404",
+		"upstream-error":   "500",
+		"invalid-uuid":     "400",
+	}, outcomes)
+
+	assert.Equal(t, uint64(2), metrics["public_organisations_api_transform_duration_seconds"].GetMetric()[0].GetHistogram().GetSampleCount())
+	assert.NotEmpty(t, metrics["public_organisations_api_concepts_api_request_duration_seconds"].GetMetric())
+}
+
+func TestMetricsCountUnknowns(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := NewMetrics(registry)
+	m.observeUnknowns(ConceptApiResponse{
+		Related: []RelatedConcept{
+			{Predicate: "http://www.ft.com/ontology/subOrganisationOf"},
+			{Predicate: "http://www.ft.com/ontology/hasHeadquarters"},
+		},
+		AlternativeLabels: []TypedValue{
+			{Type: "http://www.ft.com/ontology/properName"},
+			{Type: "http://www.ft.com/ontology/TradeName"},
+		},
+	})
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	unknowns := map[string]string{}
+	for _, f := range families {
+		if f.GetType() != dto.MetricType_COUNTER {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			unknowns[f.GetName()] = metric.GetLabel()[0].GetValue()
+		}
+	}
+	assert.Equal(t, "http://www.ft.com/ontology/hasHeadquarters", unknowns["public_organisations_api_unknown_predicates_total"])
+	assert.Equal(t, "http://www.ft.com/ontology/TradeName", unknowns["public_organisations_api_unknown_label_types_total"])
+}
@@ -1,5 +1,7 @@
 package organisations
 
+import "time"
+
 // Thing is the base entity, all nodes in neo4j should have these properties
 /* The following is currently defined in Java (3da1b900b38)
 @JsonInclude(NON_EMPTY)
@@ -28,6 +30,7 @@ public class Organisation extends Thing {
     public List<Membership> memberships = new ArrayList<>(); - except membership, which has been removed from the response
 }
 */
+// Organisation is version 1 of the API and is frozen, changes to the response shape go into OrganisationV2.
 type Organisation struct {
 	Thing
 	ProperName             string               `json:"properName,omitempty"`
@@ -40,6 +43,7 @@ type Organisation struct {
 	Types                  []string             `json:"types"`
 	DirectType             string               `json:"directType,omitempty"`
 	Labels                 []string             `json:"labels,omitempty"`
+	LabelDetails           []LabelDetail        `json:"labelDetails,omitempty"`
 	LegalEntityIdentifier  string               `json:"leiCode,omitempty"`
 	Parent                 *Parent              `json:"parentOrganisation,omitempty"`
 	Subsidiaries           []Subsidiary         `json:"subsidiaries,omitempty"`
@@ -47,6 +51,12 @@ type Organisation struct {
 	IsDeprecated           bool                 `json:"isDeprecated,omitempty"`
 }
 
+// LabelDetail is a label of an organisation together with its normalised label type
+type LabelDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
 // Parent is a simplified representation of a parent organisation, used in Organisation API
 type Parent struct {
 	Thing
@@ -68,6 +78,58 @@ type FinancialInstrument struct {
 	Figi       string   `json:"FIGI"`
 }
 
+// Identifiers is the concordance of every identifier known for an organisation, used in the identifiers endpoint
+type Identifiers struct {
+	Thing
+	CanonicalUUID  string       `json:"canonicalUUID"`
+	AlternateUUIDs []string     `json:"alternateUUIDs,omitempty"`
+	Identifiers    []Identifier `json:"identifiers,omitempty"`
+}
+
+// Identifier is a value assigned to an organisation by an authority such as FactSet or TME
+type Identifier struct {
+	Authority       string `json:"authority"`
+	IdentifierValue string `json:"identifierValue"`
+}
+
+// Concordances is the result of looking up organisations by authority identifiers
+type Concordances struct {
+	Concordances []Concordance `json:"concordances"`
+}
+
+// Concordance links an authority identifier to the canonical organisation it identifies
+type Concordance struct {
+	Identifier   Identifier `json:"identifier"`
+	Organisation Thing      `json:"organisation"`
+}
+
+// Snapshot is a version of an organisation as it was served at a point in time
+type Snapshot struct {
+	Timestamp    time.Time    `json:"timestamp"`
+	Organisation Organisation `json:"organisation"`
+}
+
+// History lists the versions of an organisation, oldest first, used in the history endpoint
+type History struct {
+	ID       string    `json:"id"`
+	APIURL   string    `json:"apiUrl"`
+	Versions []Version `json:"versions"`
+}
+
+// Version is a snapshot together with the fields that changed since the previous version
+type Version struct {
+	Timestamp    time.Time     `json:"timestamp"`
+	Changes      []FieldChange `json:"changes,omitempty"`
+	Organisation Organisation  `json:"organisation"`
+}
+
+// FieldChange is the previous and current value of a top level organisation field, absent values are omitted
+type FieldChange struct {
+	Field    string      `json:"field"`
+	Previous interface{} `json:"previous,omitempty"`
+	Current  interface{} `json:"current,omitempty"`
+}
+
 type TypedValue struct {
 	Type  string `json:"type"`
 	Value string `json:"value"`
@@ -87,11 +149,17 @@ type ConceptApiResponse struct {
 	YearFounded            int              `json:"yearFounded,omitempty"`
 	AlternativeLabels      []TypedValue     `json:"alternativeLabels,omitempty"`
 	IsDeprecated           bool             `json:"isDeprecated,omitempty"`
+	Identifiers            []Identifier     `json:"identifiers,omitempty"`
+}
+
+// ConceptSearchResponse is the response of public-concepts-api when searching concepts by identifier
+type ConceptSearchResponse struct {
+	Concepts []ConceptApiResponse `json:"concepts"`
 }
 
 type RelatedConcept struct {
-	Concept   Concept `json:concept,omitempty`
-	Predicate string  `json:predicate,omitempty`
+	Concept   Concept `json:"concept,omitempty"`
+	Predicate string  `json:"predicate,omitempty"`
 }
 
 type Concept struct {
@@ -101,3 +169,38 @@ type Concept struct {
 	Type      string `json:"type,omitempty"`
 	Figi      string `json:"figiCode,omitempty"`
 }
+
+// Subscription registers a callback URL for changes to a set of organisations. The secret signing its webhooks is
+// only returned when the subscription is created.
+type Subscription struct {
+	ID          string    `json:"id"`
+	CallbackURL string    `json:"callbackURL"`
+	UUIDs       []string  `json:"uuids"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Owner is the subject of the caller who subscribed, the only caller who can see or delete the subscription
+	Owner string `json:"owner"`
+	// Scopes are those of the caller who subscribed, limiting the fields of the organisations in its webhooks
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// WebhookPayload is the body of a webhook sent when a subscribed organisation changes
+type WebhookPayload struct {
+	ID             string        `json:"id"`
+	Event          string        `json:"event"`
+	SubscriptionID string        `json:"subscriptionId"`
+	UUID           string        `json:"uuid"`
+	DetectedAt     time.Time     `json:"detectedAt"`
+	Changes        []FieldChange `json:"changes"`
+	Organisation   Organisation  `json:"organisation"`
+}
+
+// DeadLetter is a webhook that could not be delivered
+type DeadLetter struct {
+	CallbackURL string         `json:"callbackURL"`
+	Attempts    int            `json:"attempts"`
+	LastStatus  int            `json:"lastStatus,omitempty"`
+	LastError   string         `json:"lastError"`
+	FailedAt    time.Time      `json:"failedAt"`
+	Payload     WebhookPayload `json:"payload"`
+}
@@ -0,0 +1,156 @@
+package organisations
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ResponseFormat is a media type responses can be written in
+type ResponseFormat struct {
+	// MediaType is matched against the Accept header of requests, e.g. application/json
+	MediaType string
+	// ContentType is the Content-Type header of responses, e.g. application/json; charset=UTF-8
+	ContentType string
+	Encode      func(w io.Writer, v interface{}) error
+}
+
+// JSONFormat writes responses as JSON, it is the format used when a request has no Accept header
+var JSONFormat = ResponseFormat{
+	MediaType:   "application/json",
+	ContentType: jsonMediaType,
+	Encode: func(w io.Writer, v interface{}) error {
+		return json.NewEncoder(w).Encode(v)
+	},
+}
+
+// ResponseFormats is a registry of the formats responses can be written in, in order of preference
+type ResponseFormats struct {
+	formats []ResponseFormat
+}
+
+// NewResponseFormats creates a registry of the formats, the first is preferred when a request accepts several
+func NewResponseFormats(formats ...ResponseFormat) *ResponseFormats {
+	f := &ResponseFormats{}
+	for _, format := range formats {
+		f.Register(format)
+	}
+	return f
+}
+
+// WithResponseFormat writes organisations in the format when requests accept it, besides JSON
+func WithResponseFormat(format ResponseFormat) HandlerOption {
+	return func(h *OrganisationsHandler) {
+		h.formats.Register(format)
+	}
+}
+
+// Register adds the format, replacing any format registered for the same media type
+func (f *ResponseFormats) Register(format ResponseFormat) {
+	format.MediaType = strings.ToLower(format.MediaType)
+	for i, registered := range f.formats {
+		if registered.MediaType == format.MediaType {
+			f.formats[i] = format
+			return
+		}
+	}
+	f.formats = append(f.formats, format)
+}
+
+// MediaTypes lists the media types of the formats in order of preference
+func (f *ResponseFormats) MediaTypes() []string {
+	mediaTypes := make([]string, 0, len(f.formats))
+	for _, format := range f.formats {
+		mediaTypes = append(mediaTypes, format.MediaType)
+	}
+	return mediaTypes
+}
+
+// Negotiate picks the format with the highest quality in the Accept header, preferring earlier formats when several
+// have the same quality. The quality of a format is that of the most specific media range matching it. ok is false
+// when the header accepts none of the formats, a request without one accepts any.
+func (f *ResponseFormats) Negotiate(accept string) (format ResponseFormat, ok bool) {
+	ranges := parseAccept(accept)
+	if strings.TrimSpace(accept) == "" {
+		ranges = []mediaRange{{mediaType: "*", subtype: "*", quality: 1}}
+	}
+	best := 0.0
+	for _, candidate := range f.formats {
+		quality := formatQuality(candidate.MediaType, ranges)
+		if quality > best {
+			format, best, ok = candidate, quality, true
+		}
+	}
+	return format, ok
+}
+
+// mediaRange is a media range of an Accept header with its quality, either type or subtype may be *
+type mediaRange struct {
+	mediaType string
+	subtype   string
+	quality   float64
+}
+
+// parseAccept reads the media ranges of an Accept header, leaving out any that cannot be parsed
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, element := range strings.Split(accept, ",") {
+		params := strings.Split(element, ";")
+		mediaType, subtype, found := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !found || mediaType == "" || subtype == "" || (mediaType == "*" && subtype != "*") {
+			continue
+		}
+		r := mediaRange{mediaType: mediaType, subtype: subtype, quality: 1}
+		valid := true
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(strings.TrimSpace(name)) != "q" {
+				continue
+			}
+			quality, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || quality < 0 || quality > 1 {
+				valid = false
+				break
+			}
+			r.quality = quality
+		}
+		if valid {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+func formatQuality(mediaType string, ranges []mediaRange) float64 {
+	formatType, formatSubtype, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.mediaType == formatType && r.subtype == formatSubtype:
+			s = 2
+		case r.mediaType == formatType && r.subtype == "*":
+			s = 1
+		case r.mediaType == "*":
+			s = 0
+		}
+		if s > specificity {
+			quality, specificity = r.quality, s
+		}
+	}
+	return quality
+}
+
+// negotiateFormat picks the format to write the response to r in, and says so in the Vary header. ok is false when r
+// accepts none of the formats.
+func (h *OrganisationsHandler) negotiateFormat(w http.ResponseWriter, r *http.Request) (format ResponseFormat, ok bool) {
+	w.Header().Add("Vary", "Accept")
+	return h.formats.Negotiate(r.Header.Get("Accept"))
+}
+
+// notAcceptableMessage lists the supported media types for the body of a 406
+func notAcceptableMessage(formats *ResponseFormats) string {
+	return "none of the accepted media types is supported, supported media types are " + strings.Join(formats.MediaTypes(), ", ")
+}
@@ -0,0 +1,87 @@
+package organisations
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+var xmlFormat = ResponseFormat{
+	MediaType:   "application/xml",
+	ContentType: "application/xml; charset=UTF-8",
+	Encode: func(w io.Writer, v interface{}) error {
+		return xml.NewEncoder(w).Encode(v)
+	},
+}
+
+func TestNegotiate(t *testing.T) {
+	formats := NewResponseFormats(JSONFormat, xmlFormat)
+	for _, test := range []struct {
+		accept        string
+		expectedType  string
+		expectedFound bool
+	}{
+		{"", "application/json", true},
+		{"*/*", "application/json", true},
+		{"application/xml", "application/xml", true},
+		{"APPLICATION/XML", "application/xml", true},
+		{"application/json;q=0.5, application/xml", "application/xml", true},
+		{"application/*;q=0.2, application/xml;q=0.1", "application/json", true},
+		{"application/xml;q=0.8, */*;q=0.9", "application/json", true},
+		{"text/html, application/xml;q=0", "", false},
+		{"application/json;q=0, */*", "application/xml", true},
+		{"application/json;charset=UTF-8", "application/json", true},
+		{"application/xml;q=2, application/json;q=0.1", "application/json", true},
+		{"text/html", "", false},
+		{"nonsense", "", false},
+	} {
+		format, found := formats.Negotiate(test.accept)
+		assert.Equal(t, test.expectedFound, found, test.accept)
+		assert.Equal(t, test.expectedType, format.MediaType, test.accept)
+	}
+
+	formats.Register(ResponseFormat{MediaType: "Application/JSON", ContentType: "application/json"})
+	assert.Equal(t, []string{"application/json", "application/xml"}, formats.MediaTypes())
+}
+
+func TestGetOrganisationNegotiatesFormat(t *testing.T) {
+	source := newTestMemorySource(t, getBasicOrganisationAsConcept)
+	h := NewHandler(nil, "", logger.NewUPPInfoLogger("tests"), WithSource(source), WithResponseFormat(xmlFormat))
+	router := mux.NewRouter()
+	h.RegisterHandlers(router)
+
+	for _, test := range []struct {
+		url                 string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{"/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", "application/xml", http.StatusOK, "application/xml; charset=UTF-8",
+			"<Organisation><ID>http://api.ft.com/things/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6</ID>"},
+		{"/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", "application/json, application/xml;q=0.9", http.StatusOK, jsonMediaType,
+			`"prefLabel":"Google Inc"`},
+		{"/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", "text/html", http.StatusNotAcceptable, problemMediaType,
+			`"detail":"none of the accepted media types is supported, supported media types are application/json, application/xml"`},
+		{"/v2/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", "text/html", http.StatusNotAcceptable, problemMediaType,
+			`"code":"not-acceptable"`},
+		{"/v2/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", "application/xml", http.StatusOK, "application/xml; charset=UTF-8",
+			"<OrganisationV2>"},
+	} {
+		req := httptest.NewRequest("GET", test.url, nil)
+		req.Header.Set("Accept", test.accept)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, test.expectedCode, rec.Code, test.accept)
+		assert.Equal(t, test.expectedContentType, rec.Header().Get("Content-Type"), test.accept)
+		assert.Equal(t, "Accept", rec.Header().Get("Vary"), test.accept)
+		assert.Contains(t, rec.Body.String(), test.expectedBody, test.accept)
+	}
+}
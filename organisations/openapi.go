@@ -0,0 +1,500 @@
+package organisations
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+)
+
+const (
+	openAPIVersion = "3.0.3"
+	// APIVersion is the version of the API described by the OpenAPI document and _ft/api.yml
+	APIVersion     = "2.0.1"
+	jsonMediaType  = "application/json; charset=UTF-8"
+	bearerSecurity = "bearerToken"
+)
+
+var pathParameter = regexp.MustCompile(`{([^}:]+)(:[^}]+)?}`)
+
+// OpenAPIDocument is an OpenAPI 3 description of the API
+type OpenAPIDocument struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       OpenAPIInfo                     `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components OpenAPIComponents               `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*OpenAPISchema `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+type Operation struct {
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPISchema is the subset of the OpenAPI schema object generated from Go types
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// operationDoc documents an operation of a route, its response bodies are described by the types of the values
+type operationDoc struct {
+	summary   string
+	tag       string
+	query     []Parameter
+	request   interface{}
+	responses map[int]responseDoc
+	protected bool
+}
+
+type responseDoc struct {
+	description string
+	body        interface{}
+}
+
+var (
+	uuidParameter        = &OpenAPISchema{Type: "string", Format: "uuid"}
+	stringParameter      = &OpenAPISchema{Type: "string"}
+	booleanParameter     = &OpenAPISchema{Type: "boolean"}
+	invalidUUIDResponse  = responseDoc{"The uuid path parameter has an unexpected format.", Problem{}}
+	notFoundResponse     = responseDoc{"There is no organisation with the uuid.", Problem{}}
+	serverErrorResponse  = responseDoc{"There was an issue processing the records.", Problem{}}
+	unauthorisedResponse = responseDoc{"The bearer token is missing or invalid.", Problem{}}
+)
+
+// operationDocs documents every operation the handlers may register, by method and path template
+var operationDocs = map[string]operationDoc{
+	"GET /organisations/{uuid}": {
+		summary: "Retrieves an Organisation for the given UUID.",
+		tag:     "Public API",
+		query: []Parameter{{Name: showLabelDetailsParam, In: "query", Schema: booleanParameter,
+			Description: "When true the response includes labelDetails, every alternative label with its normalised type."}},
+		responses: map[int]responseDoc{
+			http.StatusOK:                  {"The organisation.", Organisation{}},
+			http.StatusMovedPermanently:    {"The uuid is an alternate UUID of the organisation, redirects to its canonical UUID.", nil},
+			http.StatusBadRequest:          invalidUUIDResponse,
+			http.StatusNotFound:            notFoundResponse,
+			http.StatusNotAcceptable:       {"The Accept header accepts none of the supported media types, which are listed in the detail.", Problem{}},
+			http.StatusInternalServerError: serverErrorResponse,
+		},
+	},
+	"GET /v2/organisations/{uuid}": {
+		summary: "Retrieves version 2 of an Organisation for the given UUID.",
+		tag:     "Public API",
+		responses: map[int]responseDoc{
+			http.StatusOK:                  {"The organisation.", OrganisationV2{}},
+			http.StatusMovedPermanently:    {"The uuid is an alternate UUID of the organisation, redirects to its canonical UUID.", nil},
+			http.StatusBadRequest:          {"The uuid path parameter has an unexpected format.", Problem{}},
+			http.StatusNotFound:            {"There is no organisation with the uuid.", Problem{}},
+			http.StatusNotAcceptable:       {"The Accept header accepts none of the supported media types, which are listed in the detail.", Problem{}},
+			http.StatusInternalServerError: {"There was an issue processing the records.", Problem{}},
+		},
+	},
+	"GET /organisations": {
+		summary: "Looks up Organisations by authority identifiers.",
+		tag:     "Public API",
+		query: []Parameter{
+			{Name: "authority", In: "query", Required: true, Schema: stringParameter,
+				Description: "Name or URI of the authority, e.g. FACTSET, TME, LEI or http://api.ft.com/system/FACTSET"},
+			{Name: "identifierValue", In: "query", Required: true, Schema: &OpenAPISchema{Type: "array", Items: stringParameter},
+				Description: "Identifier values assigned by the authority. May be repeated or comma separated, up to 100 values."},
+		},
+		responses: map[int]responseDoc{
+			http.StatusOK:                  {"The concordances found when several identifier values are given.", Concordances{}},
+			http.StatusMovedPermanently:    {"Redirects to the canonical organisation when a single identifier value is found.", nil},
+			http.StatusBadRequest:          {"The authority or identifierValue parameters are missing, or too many values are given.", Problem{}},
+			http.StatusNotFound:            {"A single identifier value does not identify an organisation.", Problem{}},
+			http.StatusInternalServerError: serverErrorResponse,
+		},
+	},
+	"GET /organisations/{uuid}/identifiers": {
+		summary: "Retrieves the identifier concordance of an Organisation for the given UUID.",
+		tag:     "Public API",
+		responses: map[int]responseDoc{
+			http.StatusOK:                  {"The identifiers of the organisation.", Identifiers{}},
+			http.StatusMovedPermanently:    {"Redirects to the canonical UUID if the uuid is an alternate one.", nil},
+			http.StatusBadRequest:          invalidUUIDResponse,
+			http.StatusNotFound:            notFoundResponse,
+			http.StatusInternalServerError: serverErrorResponse,
+		},
+	},
+	"GET /organisations/{uuid}/history": {
+		summary: "Lists the versions of an Organisation served, oldest first.",
+		tag:     "Public API",
+		responses: map[int]responseDoc{
+			http.StatusOK:                  {"The versions of the organisation with the fields that changed in each.", History{}},
+			http.StatusBadRequest:          invalidUUIDResponse,
+			http.StatusNotFound:            {"The organisation has no history.", Problem{}},
+			http.StatusInternalServerError: serverErrorResponse,
+		},
+	},
+	"GET /__admin/cache": {
+		summary:   "Returns the statistics of the organisation cache.",
+		tag:       "Admin",
+		protected: true,
+		responses: map[int]responseDoc{
+			http.StatusOK:           {"The cache statistics.", CacheStats{}},
+			http.StatusUnauthorized: unauthorisedResponse,
+		},
+	},
+	"DELETE /__admin/cache": {
+		summary:   "Purges every cached organisation, or those whose UUID starts with a prefix.",
+		tag:       "Admin",
+		protected: true,
+		query: []Parameter{
+			{Name: "all", In: "query", Schema: booleanParameter, Description: "Purge every organisation"},
+			{Name: "prefix", In: "query", Schema: stringParameter, Description: "Purge the organisations whose UUID starts with the prefix"},
+		},
+		responses: map[int]responseDoc{
+			http.StatusOK:           {"The number of organisations purged and their surrogate keys.", PurgeResult{}},
+			http.StatusBadRequest:   {"Neither all nor a valid prefix is given.", Problem{}},
+			http.StatusUnauthorized: unauthorisedResponse,
+		},
+	},
+	"GET /__admin/cache/{uuid}": {
+		summary:   "Returns a cached organisation.",
+		tag:       "Admin",
+		protected: true,
+		responses: map[int]responseDoc{
+			http.StatusOK:           {"The cache entry.", CacheEntry{}},
+			http.StatusNotFound:     {"The organisation is not cached.", Problem{}},
+			http.StatusUnauthorized: unauthorisedResponse,
+		},
+	},
+	"DELETE /__admin/cache/{uuid}": {
+		summary:   "Purges a cached organisation.",
+		tag:       "Admin",
+		protected: true,
+		responses: map[int]responseDoc{
+			http.StatusOK:           {"The number of organisations purged and their surrogate keys.", PurgeResult{}},
+			http.StatusBadRequest:   invalidUUIDResponse,
+			http.StatusUnauthorized: unauthorisedResponse,
+		},
+	},
+	"GET /webhooks/subscriptions": {
+		summary:   "Lists the webhook subscriptions.",
+		tag:       "Webhooks",
+		protected: true,
+		responses: map[int]responseDoc{
+			http.StatusOK:           {"The subscriptions, without their secrets.", []Subscription{}},
+			http.StatusUnauthorized: unauthorisedResponse,
+		},
+	},
+	"POST /webhooks/subscriptions": {
+		summary:   "Subscribes a callback URL to changes of organisations.",
+		tag:       "Webhooks",
+		protected: true,
+		request:   Subscription{},
+		responses: map[int]responseDoc{
+			http.StatusCreated:             {"The subscription, with the secret signing its webhooks.", Subscription{}},
+			http.StatusBadRequest:          {"The subscription is invalid.", Problem{}},
+			http.StatusUnauthorized:        unauthorisedResponse,
+			http.StatusInternalServerError: {"The subscription could not be created.", Problem{}},
+		},
+	},
+	"GET /webhooks/subscriptions/{id}": {
+		summary:   "Returns a webhook subscription.",
+		tag:       "Webhooks",
+		protected: true,
+		responses: map[int]responseDoc{
+			http.StatusOK:           {"The subscription, without its secret.", Subscription{}},
+			http.StatusNotFound:     {"There is no subscription with the id.", Problem{}},
+			http.StatusUnauthorized: unauthorisedResponse,
+		},
+	},
+	"DELETE /webhooks/subscriptions/{id}": {
+		summary:   "Deletes a webhook subscription.",
+		tag:       "Webhooks",
+		protected: true,
+		responses: map[int]responseDoc{
+			http.StatusNoContent:    {"The subscription was deleted.", nil},
+			http.StatusNotFound:     {"There is no subscription with the id.", Problem{}},
+			http.StatusUnauthorized: unauthorisedResponse,
+		},
+	},
+	"GET /webhooks/dead-letters": {
+		summary:   "Lists the webhooks that could not be delivered.",
+		tag:       "Webhooks",
+		protected: true,
+		query:     []Parameter{{Name: "subscription", In: "query", Schema: stringParameter, Description: "Only list the webhooks of the subscription"}},
+		responses: map[int]responseDoc{
+			http.StatusOK:           {"The undelivered webhooks.", []DeadLetter{}},
+			http.StatusUnauthorized: unauthorisedResponse,
+		},
+	},
+	"GET /__health": {
+		summary: "Runs application healthchecks and returns FT Healthcheck style json.",
+		tag:     "Health",
+		responses: map[int]responseDoc{
+			http.StatusOK: {"The output of the healthchecks, see the ok property for whether the application is healthy.", fthealth.HealthResult{}},
+		},
+	},
+	"GET /__gtg": {
+		summary: "Lightly healthchecks the application, and returns a 200 if it's Good-To-Go.",
+		tag:     "Health",
+		responses: map[int]responseDoc{
+			http.StatusOK:                 {"The application is good to go.", nil},
+			http.StatusServiceUnavailable: {"The application is not good to go, see /__health.", nil},
+		},
+	},
+	"GET /__api": {
+		summary: "Returns this OpenAPI document.",
+		tag:     "Info",
+		responses: map[int]responseDoc{
+			http.StatusOK: {"The OpenAPI document of the API.", nil},
+		},
+	},
+}
+
+// GenerateOpenAPI describes the routes registered on router with the types of their responses. undocumented lists the
+// operations missing from operationDocs, which are described without their responses.
+func GenerateOpenAPI(router *mux.Router) (doc OpenAPIDocument, undocumented []string, err error) {
+	doc = OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: OpenAPIInfo{
+			Title:       "Public Organisations API",
+			Description: "Public Organisations API gives access to the UPP representation of a organisation",
+			Version:     APIVersion,
+		},
+		Paths:      map[string]map[string]Operation{},
+		Components: OpenAPIComponents{Schemas: map[string]*OpenAPISchema{}},
+	}
+	g := schemaGenerator{schemas: doc.Components.Schemas}
+
+	err = router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		path := pathParameter.ReplaceAllString(template, "{$1}")
+		for _, method := range routeMethods(route, doc.Paths[path] != nil) {
+			operation, documented := g.operation(method, path)
+			if !documented {
+				undocumented = append(undocumented, method+" "+path)
+			}
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = map[string]Operation{}
+			}
+			doc.Paths[path][strings.ToLower(method)] = operation
+			if operation.Security != nil {
+				doc.Components.SecuritySchemes = map[string]SecurityScheme{bearerSecurity: {Type: "http", Scheme: "bearer"}}
+			}
+		}
+		return nil
+	})
+	return doc, undocumented, err
+}
+
+// routeMethods lists the methods a route serves. Routes without methods serve GET, unless they only answer the
+// methods not allowed on a path that was already seen.
+func routeMethods(route *mux.Route, seen bool) []string {
+	if methodHandler, ok := route.GetHandler().(methodHandler); ok {
+		methods := make([]string, 0, len(methodHandler))
+		for method := range methodHandler {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		return methods
+	}
+	if methods, err := route.GetMethods(); err == nil {
+		return methods
+	}
+	if seen {
+		return nil
+	}
+	return []string{http.MethodGet}
+}
+
+type schemaGenerator struct {
+	schemas map[string]*OpenAPISchema
+}
+
+func (g schemaGenerator) operation(method string, path string) (Operation, bool) {
+	doc, documented := operationDocs[method+" "+path]
+	operation := Operation{Summary: doc.summary, Responses: map[string]Response{}}
+	if doc.tag != "" {
+		operation.Tags = []string{doc.tag}
+	}
+	for _, match := range pathParameter.FindAllStringSubmatch(path, -1) {
+		schema := stringParameter
+		if match[1] == "uuid" {
+			schema = uuidParameter
+		}
+		operation.Parameters = append(operation.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: schema})
+	}
+	operation.Parameters = append(operation.Parameters, doc.query...)
+	if doc.request != nil {
+		operation.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/json": {Schema: g.schema(reflect.TypeOf(doc.request))},
+		}}
+	}
+	for status, response := range doc.responses {
+		r := Response{Description: response.description}
+		if response.body != nil {
+			mediaType := jsonMediaType
+			if _, ok := response.body.(Problem); ok {
+				mediaType = problemMediaType
+			}
+			r.Content = map[string]MediaType{mediaType: {Schema: g.schema(reflect.TypeOf(response.body))}}
+		}
+		operation.Responses[strconv.Itoa(status)] = r
+	}
+	if !documented {
+		operation.Responses["default"] = Response{Description: "Undocumented"}
+	}
+	if doc.protected {
+		operation.Security = []map[string][]string{{bearerSecurity: {}}}
+	}
+	return operation, documented
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema describes the JSON encoding of values of type t, named structs are added to the components and referenced
+func (g schemaGenerator) schema(t reflect.Type) *OpenAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = &OpenAPISchema{}
+			*g.schemas[t.Name()] = *g.object(t)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + t.Name()}
+	case t.Kind() == reflect.Struct:
+		return g.object(t)
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: g.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case t.Kind() == reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &OpenAPISchema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &OpenAPISchema{Type: "number"}
+	default:
+		return &OpenAPISchema{}
+	}
+}
+
+// object describes a struct by its JSON fields, flattening embedded structs. Fields without omitempty are required.
+func (g schemaGenerator) object(t reflect.Type) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := g.object(field.Type)
+			for property, propertySchema := range embedded.Properties {
+				schema.Properties[property] = propertySchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	sort.Strings(schema.Required)
+	return schema
+}
+
+// OpenAPIHandler serves the OpenAPI document of the routes of router, generated on the first request so that it
+// includes every route registered by then
+func OpenAPIHandler(router *mux.Router, ftLogger *logger.UPPLogger) http.HandlerFunc {
+	var once sync.Once
+	var body []byte
+	var err error
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			var doc OpenAPIDocument
+			var undocumented []string
+			doc, undocumented, err = GenerateOpenAPI(router)
+			if err == nil {
+				body, err = json.MarshalIndent(doc, "", "  ")
+			}
+			if len(undocumented) > 0 {
+				ftLogger.WithField("operations", undocumented).Warn("OpenAPI document has undocumented operations")
+			}
+		})
+		if err != nil {
+			ftLogger.WithError(err).Error("failed to generate OpenAPI document")
+			writeProblem(w, r, http.StatusInternalServerError, errorInternal, "failed to generate OpenAPI document")
+			return
+		}
+		w.Header().Set("Content-Type", jsonMediaType)
+		w.Write(body)
+	}
+}
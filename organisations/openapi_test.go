@@ -0,0 +1,180 @@
+package organisations
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// swaggerDefinition is the part of _ft/api.yml compared with the generated OpenAPI document
+type swaggerDefinition struct {
+	Info struct {
+		Version string `yaml:"version"`
+	} `yaml:"info"`
+	Paths map[string]map[string]struct {
+		Tags      []string `yaml:"tags"`
+		Responses map[string]struct {
+			Examples map[string]map[string]interface{} `yaml:"examples"`
+		} `yaml:"responses"`
+	} `yaml:"paths"`
+	Definitions map[string]*Schema `yaml:"definitions"`
+}
+
+func loadSwaggerDefinition(t *testing.T) swaggerDefinition {
+	data, err := os.ReadFile("../_ft/api.yml")
+	require.NoError(t, err)
+	var swagger swaggerDefinition
+	require.NoError(t, yaml.Unmarshal(data, &swagger))
+	return swagger
+}
+
+// newTestAPIRouter registers every route the service may serve
+func newTestAPIRouter(t *testing.T, withOptional bool) (*mux.Router, *OrganisationsHandler) {
+	log := logger.NewUPPInfoLogger("tests")
+	var opts []HandlerOption
+	if withOptional {
+		webhooks, err := NewWebhooks(http.DefaultClient, NewMemoryWebhookStore(), WebhookConfig{}, log, prometheus.NewRegistry())
+		require.NoError(t, err)
+		opts = append(opts,
+			WithHistoryStore(newTestHistoryStore(t)),
+			WithCache(NewOrganisationCache(time.Minute, 10)),
+			WithWebhooks(webhooks))
+	}
+	h := NewHandler(&mockHTTPClient{statusCode: 404}, "localhost:8080/concepts", log, opts...)
+	router := mux.NewRouter()
+	router.HandleFunc("/__health", func(w http.ResponseWriter, r *http.Request) {})
+	router.HandleFunc("/__api", OpenAPIHandler(router, log))
+	h.RegisterHandlers(router)
+	h.RegisterAdminHandlers(router, "secret")
+	h.RegisterWebhookHandlers(router, "secret")
+	router.HandleFunc("/__gtg", func(w http.ResponseWriter, r *http.Request) {})
+	return router, &h
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	router, _ := newTestAPIRouter(t, true)
+	doc, undocumented, err := GenerateOpenAPI(router)
+	require.NoError(t, err)
+	assert.Empty(t, undocumented, "add the operations to operationDocs")
+
+	get := doc.Paths["/organisations/{uuid}"]["get"]
+	assert.Equal(t, "#/components/schemas/Organisation", get.Responses["200"].Content[jsonMediaType].Schema.Ref)
+	assert.Equal(t, []Parameter{
+		{Name: "uuid", In: "path", Required: true, Schema: uuidParameter},
+		{Name: "showLabelDetails", In: "query", Schema: booleanParameter, Description: get.Parameters[1].Description},
+	}, get.Parameters)
+	assert.NotContains(t, doc.Paths["/organisations/{uuid}"], "post", "method not allowed routes are not operations")
+
+	organisation := doc.Components.Schemas["Organisation"]
+	assert.Equal(t, []string{"apiUrl", "id", "types"}, organisation.Required)
+	assert.Equal(t, "#/components/schemas/FinancialInstrument", organisation.Properties["financialInstrument"].Ref)
+	assert.Equal(t, "boolean", organisation.Properties["isDeprecated"].Type)
+	assert.Equal(t, "#/components/schemas/Parent", organisation.Properties["parentOrganisation"].Ref)
+
+	assert.Equal(t, []map[string][]string{{bearerSecurity: {}}}, doc.Paths["/__admin/cache"]["delete"].Security)
+	assert.Equal(t, "date-time", doc.Components.Schemas["Subscription"].Properties["createdAt"].Format)
+	assert.Nil(t, doc.Paths["/organisations/{uuid}"]["get"].Security)
+}
+
+func TestOpenAPIMatchesSwaggerDefinition(t *testing.T) {
+	swagger := loadSwaggerDefinition(t)
+	router, h := newTestAPIRouter(t, false)
+	doc, _, err := GenerateOpenAPI(router)
+	require.NoError(t, err)
+
+	assert.Equal(t, swagger.Info.Version, doc.Info.Version)
+
+	var swaggerOperations, generatedOperations []string
+	for path, operations := range swagger.Paths {
+		for method, operation := range operations {
+			if len(operation.Tags) > 0 && operation.Tags[0] == "Public API" {
+				swaggerOperations = append(swaggerOperations, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	for path, operations := range doc.Paths {
+		for method, operation := range operations {
+			if len(operation.Tags) > 0 && operation.Tags[0] == "Public API" {
+				generatedOperations = append(generatedOperations, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(swaggerOperations)
+	sort.Strings(generatedOperations)
+	assert.Equal(t, generatedOperations, swaggerOperations, "_ft/api.yml should document every public route")
+
+	assertSchemasAgree(t, "Organisation", swagger.Definitions, swagger.Definitions["Organisation"], doc.Components.Schemas, doc.Components.Schemas["Organisation"])
+	assertSchemasAgree(t, "OrganisationV2", swagger.Definitions, swagger.Definitions["OrganisationV2"], doc.Components.Schemas, doc.Components.Schemas["OrganisationV2"])
+	assertSchemasAgree(t, "Problem", swagger.Definitions, swagger.Definitions["Problem"], doc.Components.Schemas, doc.Components.Schemas["Problem"])
+
+	example := swagger.Paths["/__health"]["get"].Responses["200"].Examples["application/json"]
+	check := example["checks"].([]interface{})[0].(map[string]interface{})
+	expected := h.HealthCheck()
+	assert.Equal(t, expected.ID, check["id"])
+	assert.Equal(t, expected.Name, check["name"])
+	assert.Equal(t, expected.BusinessImpact, check["businessImpact"])
+	assert.Equal(t, expected.TechnicalSummary, check["technicalSummary"])
+	assert.Equal(t, expected.PanicGuide, check["panicGuide"])
+	assert.EqualValues(t, expected.Severity, check["severity"])
+}
+
+// assertSchemasAgree checks a definition of _ft/api.yml describes the same fields and types as the schema generated
+// from the Go model. The definition may require more fields than the model always encodes.
+func assertSchemasAgree(t *testing.T, path string, definitions map[string]*Schema, swagger *Schema, schemas map[string]*OpenAPISchema, generated *OpenAPISchema) {
+	for swagger.Ref != "" {
+		swagger = definitions[strings.TrimPrefix(swagger.Ref, definitionsRef)]
+	}
+	for generated.Ref != "" {
+		generated = schemas[strings.TrimPrefix(generated.Ref, "#/components/schemas/")]
+	}
+	if !assert.Equal(t, generated.Type, swagger.Type, "type of %s", path) {
+		return
+	}
+	switch generated.Type {
+	case "object":
+		var swaggerFields, generatedFields []string
+		for name := range swagger.Properties {
+			swaggerFields = append(swaggerFields, name)
+		}
+		for name := range generated.Properties {
+			generatedFields = append(generatedFields, name)
+		}
+		sort.Strings(swaggerFields)
+		sort.Strings(generatedFields)
+		if !assert.Equal(t, generatedFields, swaggerFields, "fields of %s", path) {
+			return
+		}
+		assert.Subset(t, swagger.Required, generated.Required, "%s must require the fields the model always has", path)
+		for _, name := range generatedFields {
+			assertSchemasAgree(t, path+"."+name, definitions, swagger.Properties[name], schemas, generated.Properties[name])
+		}
+	case "array":
+		assertSchemasAgree(t, path+"[]", definitions, swagger.Items, schemas, generated.Items)
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	router, _ := newTestAPIRouter(t, false)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/__api", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, jsonMediaType, rec.Header().Get("Content-Type"))
+	var doc OpenAPIDocument
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, openAPIVersion, doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/__api")
+	assert.NotContains(t, doc.Paths, "/__admin/cache", "only registered routes are documented")
+}
@@ -0,0 +1,121 @@
+package organisations
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+const problemMediaType = "application/problem+json"
+
+// Codes of the problems the API answers with. They are stable, clients should match on them rather than on the detail.
+const (
+	errorInvalidUUID         = "invalid-uuid"
+	errorInvalidParameters   = "invalid-parameters"
+	errorInvalidSubscription = "invalid-subscription"
+	errorNotFound            = "not-found"
+	errorUpstream            = "upstream-error"
+	errorContractViolation   = "contract-violation"
+	errorInternal            = "internal-error"
+	errorMethodNotAllowed    = "method-not-allowed"
+	errorNotAcceptable       = "not-acceptable"
+	errorUnauthorised        = "unauthorised"
+	errorForbidden           = "forbidden"
+	errorRateLimited         = "rate-limited"
+)
+
+// Problem is the RFC 7807 body of every error response
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	// Code identifies the kind of problem
+	Code          string `json:"code"`
+	TransactionID string `json:"transactionId,omitempty"`
+	// UpstreamStatus is the status public-concepts-api answered with, when it caused the problem
+	UpstreamStatus int `json:"upstreamStatus,omitempty"`
+}
+
+// writeProblem answers r with a problem. The headers describing a successful response are removed, so that a
+// problem written after them is not cached as the resource.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code string, detail string) {
+	writeProblemBody(w, Problem{
+		Status:        status,
+		Code:          code,
+		Detail:        detail,
+		TransactionID: transactionidutils.GetTransactionIDFromRequest(r),
+	})
+}
+
+// writeUpstreamProblem answers r with a 500 for the failure to read from public-concepts-api, with the status it
+// answered with if it did
+func writeUpstreamProblem(w http.ResponseWriter, r *http.Request, detail string, err error) {
+	problem := Problem{
+		Status:        http.StatusInternalServerError,
+		Code:          errorUpstream,
+		Detail:        detail,
+		TransactionID: transactionidutils.GetTransactionIDFromRequest(r),
+	}
+	var upstream *UpstreamError
+	if errors.As(err, &upstream) {
+		problem.UpstreamStatus = upstream.StatusCode
+	}
+	writeProblemBody(w, problem)
+}
+
+func writeProblemBody(w http.ResponseWriter, problem Problem) {
+	problem.Type = "about:blank"
+	problem.Title = http.StatusText(problem.Status)
+	body, _ := json.Marshal(problem)
+
+	w.Header().Del("Cache-Control")
+	w.Header().Del("Surrogate-Key")
+	w.Header().Set("Content-Type", problemMediaType)
+	w.WriteHeader(problem.Status)
+	w.Write(append(body, '\n'))
+}
+
+// writeEncoded encodes v in the format before writing anything, so that a failure to encode can still be answered
+// with a clean problem rather than a truncated body
+func writeEncoded(w http.ResponseWriter, format ResponseFormat, status int, v interface{}) error {
+	var body bytes.Buffer
+	if err := format.Encode(&body, v); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", format.ContentType)
+	w.WriteHeader(status)
+	w.Write(body.Bytes())
+	return nil
+}
+
+// methodHandler routes requests to the handler of their method like handlers.MethodHandler, answering the methods
+// it has no handler for with a problem
+type methodHandler map[string]http.Handler
+
+func (m methodHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler, ok := m[r.Method]; ok {
+		handler.ServeHTTP(w, r)
+		return
+	}
+	allow := make([]string, 0, len(m)+1)
+	for method := range m {
+		allow = append(allow, method)
+	}
+	if _, ok := m[http.MethodOptions]; !ok {
+		allow = append(allow, http.MethodOptions)
+	}
+	sort.Strings(allow)
+	w.Header().Set("Allow", strings.Join(allow, ", "))
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	writeProblem(w, r, http.StatusMethodNotAllowed, errorMethodNotAllowed, fmt.Sprintf("method %s is not allowed", r.Method))
+}
@@ -0,0 +1,142 @@
+package organisations
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingFormat starts writing a response before failing, as an encoder failing half way through a value does
+var failingFormat = ResponseFormat{
+	MediaType:   "application/json",
+	ContentType: jsonMediaType,
+	Encode: func(w io.Writer, v interface{}) error {
+		w.Write([]byte(`{"id":`))
+		return errors.New("encoder failed")
+	},
+}
+
+func serveProblem(t *testing.T, router http.Handler, method string, url string) (*httptest.ResponseRecorder, Problem) {
+	req := httptest.NewRequest(method, url, nil)
+	req.Header.Set("X-Request-Id", "tid_test")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var problem Problem
+	assert.Equal(t, problemMediaType, rec.Header().Get("Content-Type"), url)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem), "%s should return valid JSON, not %s", url, rec.Body.String())
+	assert.Equal(t, rec.Code, problem.Status, url)
+	assert.Equal(t, http.StatusText(rec.Code), problem.Title, url)
+	assert.Equal(t, "about:blank", problem.Type, url)
+	assert.Equal(t, "tid_test", problem.TransactionID, url)
+	return rec, problem
+}
+
+func TestProblemsQuoteTheInvalidUUID(t *testing.T) {
+	h := NewHandler(nil, "", logger.NewUPPInfoLogger("tests"), WithSource(newTestMemorySource(t, getBasicOrganisationAsConcept)),
+		WithHistoryStore(newTestHistoryStore(t)))
+	router := mux.NewRouter()
+	h.RegisterHandlers(router)
+
+	for _, url := range []string{
+		`/organisations/%22%7D,%22injected%22:%22`,
+		`/organisations/%22%7D,%22injected%22:%22/identifiers`,
+		`/organisations/%22%7D,%22injected%22:%22/history`,
+		`/v2/organisations/%22%7D,%22injected%22:%22`,
+	} {
+		rec, problem := serveProblem(t, router, "GET", url)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, url)
+		assert.Equal(t, errorInvalidUUID, problem.Code, url)
+		assert.Equal(t, `uuid '"},"injected":"' is either missing or invalid`, problem.Detail, url)
+		assert.NotContains(t, rec.Body.String(), `"injected":""`, url)
+	}
+}
+
+func TestEncodingFailuresAreCleanProblems(t *testing.T) {
+	h := NewHandler(nil, "", logger.NewUPPInfoLogger("tests"), WithSource(newTestMemorySource(t, getBasicOrganisationAsConcept)),
+		WithResponseFormat(failingFormat))
+	router := mux.NewRouter()
+	h.RegisterHandlers(router)
+
+	for _, url := range []string{"/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", "/v2/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6"} {
+		rec, problem := serveProblem(t, router, "GET", url)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code, url)
+		assert.Equal(t, errorInternal, problem.Code, url)
+		assert.Empty(t, rec.Header().Get("Cache-Control"), "%s: problems should not be cached as the organisation", url)
+		assert.Empty(t, rec.Header().Get("Surrogate-Key"), url)
+	}
+}
+
+func TestUpstreamProblemsCarryTheUpstreamStatus(t *testing.T) {
+	mockClient := &mockHTTPClient{resp: `{"message": "bad gateway"}`, statusCode: http.StatusBadGateway}
+	h := NewHandler(mockClient, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"))
+	router := mux.NewRouter()
+	h.RegisterHandlers(router)
+
+	for _, url := range []string{
+		"/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6",
+		"/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6/identifiers",
+		"/v2/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6",
+		"/organisations?authority=FACTSET&identifierValue=000C7F-E",
+	} {
+		rec, problem := serveProblem(t, router, "GET", url)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code, url)
+		assert.Equal(t, errorUpstream, problem.Code, url)
+		assert.Equal(t, http.StatusBadGateway, problem.UpstreamStatus, url)
+	}
+
+	mockClient.statusCode, mockClient.err = 0, errors.New("connection refused")
+	_, problem := serveProblem(t, router, "GET", "/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+	assert.Zero(t, problem.UpstreamStatus, "there is no upstream status when public-concepts-api did not answer")
+}
+
+func TestMethodsNotAllowedAreProblems(t *testing.T) {
+	h := NewHandler(nil, "", logger.NewUPPInfoLogger("tests"), WithSource(newTestMemorySource(t, getBasicOrganisationAsConcept)))
+	router := mux.NewRouter()
+	h.RegisterHandlers(router)
+
+	for _, url := range []string{"/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", "/v2/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6"} {
+		rec, problem := serveProblem(t, router, "DELETE", url)
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code, url)
+		assert.Equal(t, errorMethodNotAllowed, problem.Code, url)
+		assert.Equal(t, "method DELETE is not allowed", problem.Detail, url)
+		assert.Equal(t, "GET, OPTIONS", rec.Header().Get("Allow"), url)
+	}
+
+	for _, url := range []string{"/organisations", "/v2/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("OPTIONS", url, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, url)
+		assert.Equal(t, "GET, OPTIONS", rec.Header().Get("Allow"), url)
+	}
+}
+
+func TestUnroutedRequestsAreProblems(t *testing.T) {
+	h := NewHandler(nil, "", logger.NewUPPInfoLogger("tests"), WithSource(newTestMemorySource(t, getBasicOrganisationAsConcept)))
+	router := mux.NewRouter()
+	h.RegisterHandlers(router)
+	router.HandleFunc("/organisations/{uuid}/refresh", func(w http.ResponseWriter, r *http.Request) {}).Methods("POST")
+
+	rec, problem := serveProblem(t, router, "GET", "/organisation/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, errorNotFound, problem.Code)
+	assert.Equal(t, "/organisation/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6 was not found", problem.Detail)
+
+	rec, problem = serveProblem(t, router, "POST", "/organisations?authority=FACTSET&identifierValue=000C7F-E")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, errorMethodNotAllowed, problem.Code)
+	assert.Equal(t, "GET, OPTIONS", rec.Header().Get("Allow"))
+
+	rec, problem = serveProblem(t, router, "GET", "/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6/refresh")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code, "routes restricted to a method answer the others with a problem")
+	assert.Equal(t, errorMethodNotAllowed, problem.Code)
+	assert.Equal(t, "method GET is not allowed", problem.Detail)
+}
@@ -0,0 +1,267 @@
+package organisations
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	anonymousConsumer = "anonymous"
+	defaultConsumer   = "default"
+	bucketSweepPeriod = time.Minute
+	// addressPrefix keys the buckets of remote addresses apart from those of named consumers
+	addressPrefix = "address:"
+)
+
+// RateLimit allows a consumer Rate requests a second on average, in bursts of up to Burst requests.
+// The zero RateLimit is unlimited.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) unlimited() bool {
+	return l.Rate <= 0
+}
+
+// ParseRateLimit parses a limit written as rate:burst e.g. 10:20, or as a rate alone, in which case the burst
+// is the rate rounded up. An empty string is unlimited.
+func ParseRateLimit(s string) (RateLimit, error) {
+	if s == "" {
+		return RateLimit{}, nil
+	}
+	rateValue, burstValue, hasBurst := strings.Cut(s, ":")
+	rate, err := strconv.ParseFloat(rateValue, 64)
+	if err != nil || rate <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: rate must be a positive number of requests a second", s)
+	}
+	burst := int(math.Ceil(rate))
+	if hasBurst {
+		if burst, err = strconv.Atoi(burstValue); err != nil || burst < 1 {
+			return RateLimit{}, fmt.Errorf("rate limit %q: burst must be a positive number of requests", s)
+		}
+	}
+	return RateLimit{Rate: rate, Burst: burst}, nil
+}
+
+// ParseClientRateLimits parses the limits of particular consumers, each written as consumer=rate:burst
+func ParseClientRateLimits(specs []string) (map[string]RateLimit, error) {
+	limits := map[string]RateLimit{}
+	for _, spec := range specs {
+		consumer, value, ok := strings.Cut(spec, "=")
+		if !ok || consumer == "" {
+			return nil, fmt.Errorf("client rate limit %q is not written as consumer=rate:burst", spec)
+		}
+		limit, err := ParseRateLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("client %s: %w", consumer, err)
+		}
+		limits[consumer] = limit
+	}
+	return limits, nil
+}
+
+// RateLimiter throttles each consumer with a token bucket. Callers authenticated by the Authenticator are identified
+// by their subject. The headers identifying other consumers are not authenticated, so they only name a consumer with
+// a limit of its own: other values could be made up for every request, and those requests are limited by remote
+// address like the requests without any of the headers.
+type RateLimiter struct {
+	headers       []string
+	addressHeader string
+	throttled     *prometheus.CounterVec
+	now           func() time.Time
+
+	mu           sync.Mutex
+	defaultLimit RateLimit
+	clientLimits map[string]RateLimit
+	buckets      map[string]*tokenBucket
+	lastSweep    time.Time
+}
+
+// NewRateLimiter creates a limiter applying defaultLimit to every consumer without a limit of its own in clientLimits.
+// The remote address of a request is the first address of addressHeader, set by the proxies in front of the service,
+// or the address of the connection when the header is empty or absent.
+func NewRateLimiter(consumerHeaders []string, addressHeader string, defaultLimit RateLimit, clientLimits map[string]RateLimit, registerer prometheus.Registerer) *RateLimiter {
+	l := &RateLimiter{
+		headers:       consumerHeaders,
+		addressHeader: addressHeader,
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "throttled_requests_total",
+			Help:      "Requests rejected by the rate limiter by consumer, default or anonymous for requests limited by remote address.",
+		}, []string{"consumer"}),
+		now:     time.Now,
+		buckets: map[string]*tokenBucket{},
+	}
+	registerer.MustRegister(l.throttled)
+	l.SetLimits(defaultLimit, clientLimits)
+	return l
+}
+
+// SetLimits replaces the limits of the limiter. Consumers keep the tokens they have left, up to their new burst.
+func (l *RateLimiter) SetLimits(defaultLimit RateLimit, clientLimits map[string]RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.defaultLimit = defaultLimit
+	l.clientLimits = clientLimits
+}
+
+// Middleware rejects requests over the consumer's limit with 429 Too Many Requests. Admin endpoints are never limited.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/__") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		consumer, label := l.consumer(r)
+		d := l.take(consumer, label)
+		if d.limit.unlimited() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(d.limit.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.remaining))
+		w.Header().Set("RateLimit-Reset", ceilSeconds(d.reset))
+		if !d.allowed {
+			l.throttled.WithLabelValues(d.label).Inc()
+			retryAfter := ceilSeconds(d.retryAfter)
+			w.Header().Set("Retry-After", retryAfter)
+			writeProblem(w, r, http.StatusTooManyRequests, errorRateLimited, "rate limit exceeded, retry after "+retryAfter+" seconds")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// consumer returns the bucket of the request, and the label its throttled requests are counted under
+func (l *RateLimiter) consumer(r *http.Request) (consumer string, label string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		if _, ok := l.clientLimits[principal.Subject]; ok {
+			return principal.Subject, principal.Subject
+		}
+		return principal.Subject, defaultConsumer
+	}
+	label = anonymousConsumer
+	for _, header := range l.headers {
+		v := r.Header.Get(header)
+		if v == "" {
+			continue
+		}
+		if _, ok := l.clientLimits[v]; ok {
+			return v, v
+		}
+		label = defaultConsumer
+	}
+	return addressPrefix + l.remoteAddress(r), label
+}
+
+// remoteAddress returns the address the request came from, before any proxies
+func (l *RateLimiter) remoteAddress(r *http.Request) string {
+	if l.addressHeader != "" {
+		first, _, _ := strings.Cut(r.Header.Get(l.addressHeader), ",")
+		if address := strings.TrimSpace(first); address != "" {
+			return address
+		}
+	}
+	address, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return address
+}
+
+// rateDecision is the result of taking a token from a consumer's bucket
+type rateDecision struct {
+	allowed    bool
+	limit      RateLimit
+	label      string
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+func (l *RateLimiter) take(consumer string, label string) rateDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	limit := l.limitFor(consumer)
+	if limit.unlimited() {
+		return rateDecision{allowed: true, limit: limit}
+	}
+	l.sweep(now)
+
+	b, ok := l.buckets[consumer]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[consumer] = b
+	}
+	b.refill(now, limit)
+
+	d := rateDecision{limit: limit, label: label}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.allowed = true
+	} else {
+		d.retryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+	d.remaining = int(b.tokens)
+	d.reset = secondsToDuration((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return d
+}
+
+// limitFor returns the limit of the consumer
+func (l *RateLimiter) limitFor(consumer string) RateLimit {
+	if limit, ok := l.clientLimits[consumer]; ok {
+		return limit
+	}
+	return l.defaultLimit
+}
+
+// sweep forgets the buckets that have refilled, as they are no different from new ones
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketSweepPeriod {
+		return
+	}
+	l.lastSweep = now
+	for consumer, b := range l.buckets {
+		limit := l.limitFor(consumer)
+		if limit.unlimited() {
+			delete(l.buckets, consumer)
+			continue
+		}
+		b.refill(now, limit)
+		if b.tokens >= float64(limit.Burst) {
+			delete(l.buckets, consumer)
+		}
+	}
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time, limit RateLimit) {
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatFloat(math.Ceil(d.Seconds()), 'f', 0, 64)
+}
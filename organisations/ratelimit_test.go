@@ -0,0 +1,233 @@
+package organisations
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected RateLimit
+		err      bool
+	}{
+		{"", RateLimit{}, false},
+		{"10:20", RateLimit{Rate: 10, Burst: 20}, false},
+		{"0.5", RateLimit{Rate: 0.5, Burst: 1}, false},
+		{"5", RateLimit{Rate: 5, Burst: 5}, false},
+		{"0:10", RateLimit{}, true},
+		{"10:0", RateLimit{}, true},
+		{"ten", RateLimit{}, true},
+	}
+	for _, test := range tests {
+		limit, err := ParseRateLimit(test.value)
+		if test.err {
+			assert.Error(t, err, test.value)
+			continue
+		}
+		assert.NoError(t, err, test.value)
+		assert.Equal(t, test.expected, limit, test.value)
+	}
+}
+
+func TestParseClientRateLimits(t *testing.T) {
+	limits, err := ParseClientRateLimits([]string{"next-app=50:100", "search=2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]RateLimit{
+		"next-app": {Rate: 50, Burst: 100},
+		"search":   {Rate: 2, Burst: 2},
+	}, limits)
+
+	_, err = ParseClientRateLimits([]string{"next-app"})
+	assert.Error(t, err)
+}
+
+func newTestRateLimiter(defaultLimit RateLimit, clientLimits map[string]RateLimit) (*RateLimiter, *prometheus.Registry, *time.Time) {
+	registry := prometheus.NewRegistry()
+	limiter := NewRateLimiter([]string{"X-Api-Key", "X-Client-Id"}, "X-Forwarded-For", defaultLimit, clientLimits, registry)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	return limiter, registry, &now
+}
+
+func limitedRequest(limiter *RateLimiter, path string, clientID string) *httptest.ResponseRecorder {
+	return limitedRequestFrom(limiter, "192.0.2.1:1234", path, clientID)
+}
+
+func limitedRequestFrom(limiter *RateLimiter, remoteAddr string, path string, clientID string) *httptest.ResponseRecorder {
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest("GET", path, nil)
+	req.RemoteAddr = remoteAddr
+	if clientID != "" {
+		req.Header.Set("X-Client-Id", clientID)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimiterThrottlesOverBurst(t *testing.T) {
+	limiter, registry, now := newTestRateLimiter(RateLimit{Rate: 1, Burst: 2}, nil)
+
+	rec := limitedRequest(limiter, "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", "next-app")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusOK, limitedRequest(limiter, "/organisations", "next-app").Code)
+
+	rec = limitedRequest(limiter, "/organisations", "next-app")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, problemMediaType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `"code":"rate-limited"`)
+
+	assert.Equal(t, http.StatusOK, limitedRequestFrom(limiter, "192.0.2.2:1234", "/organisations", "other-app").Code, "remote addresses have their own buckets")
+
+	*now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, limitedRequest(limiter, "/organisations", "next-app").Code, "a token is added every second")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(limiter.throttled.WithLabelValues("default")))
+	count, err := testutil.GatherAndCount(registry, "public_organisations_api_throttled_requests_total")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestRateLimiterAppliesClientLimits(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(RateLimit{}, map[string]RateLimit{"search": {Rate: 1, Burst: 1}})
+
+	for i := 0; i < 5; i++ {
+		rec := limitedRequest(limiter, "/organisations", "next-app")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"), "consumers without a limit are not limited")
+	}
+
+	assert.Equal(t, http.StatusOK, limitedRequest(limiter, "/organisations", "search").Code)
+	assert.Equal(t, http.StatusTooManyRequests, limitedRequest(limiter, "/organisations", "search").Code)
+	assert.Equal(t, 1.0, testutil.ToFloat64(limiter.throttled.WithLabelValues("search")))
+}
+
+func TestRateLimiterThrottlesRotatedHeaders(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(RateLimit{Rate: 1, Burst: 2}, map[string]RateLimit{"search": {Rate: 1, Burst: 1}})
+
+	assert.Equal(t, http.StatusOK, limitedRequest(limiter, "/organisations", "made-up-1").Code)
+	assert.Equal(t, http.StatusOK, limitedRequest(limiter, "/organisations", "made-up-2").Code)
+	for i := 3; i < 10; i++ {
+		rec := limitedRequest(limiter, "/organisations", fmt.Sprintf("made-up-%d", i))
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code, "unknown consumers are limited by remote address")
+	}
+	assert.Equal(t, http.StatusTooManyRequests, limitedRequest(limiter, "/organisations", "").Code, "and share it with anonymous requests")
+	assert.Len(t, limiter.buckets, 1, "made up consumers get no bucket of their own")
+
+	assert.Equal(t, http.StatusOK, limitedRequest(limiter, "/organisations", "search").Code, "consumers with a limit of their own have their own bucket")
+	assert.Equal(t, http.StatusTooManyRequests, limitedRequest(limiter, "/organisations", "search").Code)
+	assert.Equal(t, 7.0, testutil.ToFloat64(limiter.throttled.WithLabelValues("default")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(limiter.throttled.WithLabelValues("anonymous")))
+}
+
+func TestRateLimiterSharesAnonymousBucket(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(RateLimit{Rate: 1, Burst: 1}, nil)
+
+	assert.Equal(t, http.StatusOK, limitedRequest(limiter, "/organisations", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, limitedRequest(limiter, "/organisations", "").Code)
+	assert.Equal(t, 1.0, testutil.ToFloat64(limiter.throttled.WithLabelValues("anonymous")))
+}
+
+func TestRateLimiterLimitsByForwardedAddress(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(RateLimit{Rate: 1, Burst: 1}, nil)
+	request := func(forwardedFor string) int {
+		handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest("GET", "/organisations", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("192.0.2.1, 10.0.0.2"))
+	assert.Equal(t, http.StatusTooManyRequests, request("192.0.2.1"), "the first address forwarded names the consumer")
+	assert.Equal(t, http.StatusOK, request("192.0.2.2, 10.0.0.2"), "consumers behind the same proxy have their own buckets")
+	assert.Equal(t, http.StatusOK, request(""))
+	assert.Equal(t, http.StatusTooManyRequests, request(" "), "requests without a forwarded address are limited by the address of the connection")
+	assert.ElementsMatch(t, []string{"address:192.0.2.1", "address:192.0.2.2", "address:10.0.0.1"}, mapKeys(limiter.buckets))
+}
+
+func TestRateLimiterSkipsAdminEndpoints(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(RateLimit{Rate: 1, Burst: 1}, nil)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, limitedRequest(limiter, "/__gtg", "").Code)
+	}
+}
+
+func TestRateLimiterSetLimits(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(RateLimit{Rate: 1, Burst: 1}, nil)
+
+	assert.Equal(t, http.StatusOK, limitedRequest(limiter, "/organisations", "next-app").Code)
+	assert.Equal(t, http.StatusTooManyRequests, limitedRequest(limiter, "/organisations", "next-app").Code)
+
+	limiter.SetLimits(RateLimit{}, nil)
+	assert.Equal(t, http.StatusOK, limitedRequest(limiter, "/organisations", "next-app").Code)
+}
+
+func TestRateLimiterForgetsRefilledBuckets(t *testing.T) {
+	limiter, _, now := newTestRateLimiter(RateLimit{Rate: 1, Burst: 1}, nil)
+
+	limitedRequestFrom(limiter, "192.0.2.1:1234", "/organisations", "next-app")
+	*now = now.Add(2 * bucketSweepPeriod)
+	limitedRequestFrom(limiter, "192.0.2.2:1234", "/organisations", "other-app")
+
+	assert.NotContains(t, limiter.buckets, "address:192.0.2.1")
+	assert.Contains(t, limiter.buckets, "address:192.0.2.2")
+}
+
+func TestRateLimiterLimitsAuthenticatedCallersBySubject(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(RateLimit{Rate: 1, Burst: 1}, map[string]RateLimit{"licensed-app": {Rate: 1, Burst: 2}})
+	auth := NewAuthenticator(AuthConfig{
+		APIKeys: []APIKey{{Name: "licensed-app", Key: "s3cr3t"}, {Name: "other-app", Key: "0th3r"}},
+	}, logger.NewUPPInfoLogger("tests"), prometheus.NewRegistry())
+	handler := auth.Middleware(limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	request := func(apiKey string, clientID string) int {
+		req := httptest.NewRequest("GET", "/organisations", nil)
+		req.Header.Set("X-Api-Key", apiKey)
+		req.Header.Set("X-Client-Id", clientID)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("s3cr3t", "a"))
+	assert.Equal(t, http.StatusOK, request("s3cr3t", "b"), "authenticated callers get the limit of their subject")
+	assert.Equal(t, http.StatusTooManyRequests, request("s3cr3t", "licensed-app"))
+	assert.Equal(t, http.StatusOK, request("0th3r", ""), "authenticated callers have their own bucket")
+	assert.Equal(t, http.StatusTooManyRequests, request("0th3r", "licensed-app"), "headers cannot claim the limit of another consumer")
+	assert.Equal(t, http.StatusUnauthorized, request("made-up", ""))
+	assert.ElementsMatch(t, []string{"licensed-app", "other-app"}, mapKeys(limiter.buckets))
+	assert.Equal(t, 1.0, testutil.ToFloat64(limiter.throttled.WithLabelValues("licensed-app")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(limiter.throttled.WithLabelValues("default")))
+}
+
+func mapKeys(buckets map[string]*tokenBucket) []string {
+	keys := make([]string, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	return keys
+}
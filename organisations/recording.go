@@ -0,0 +1,179 @@
+package organisations
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Financial-Times/go-logger/v2"
+)
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ErrNoRecording is returned by a ReplayClient for a request that was never recorded
+var ErrNoRecording = errors.New("no recording")
+
+// Recording is an upstream response saved by a RecordingClient
+type Recording struct {
+	Method      string          `json:"method"`
+	URL         string          `json:"url"`
+	StatusCode  int             `json:"statusCode"`
+	ContentType string          `json:"contentType,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	Text        string          `json:"text,omitempty"`
+}
+
+func (r Recording) body() []byte {
+	if len(r.Body) > 0 {
+		return r.Body
+	}
+	return []byte(r.Text)
+}
+
+// recordingFile names the file a request is recorded in. Only the path and query are used so that recordings
+// replay whichever host they were made against.
+func recordingFile(dir string, req *http.Request) string {
+	name := unsafeFileNameChars.ReplaceAllString(req.Method+req.URL.Path, "_")
+	if req.URL.RawQuery != "" {
+		sum := sha256.Sum256([]byte(req.URL.RawQuery))
+		name += "-" + hex.EncodeToString(sum[:6])
+	}
+	return filepath.Join(dir, name+".json")
+}
+
+// RecordingClient saves every response of the client it wraps in a directory, to be served later by a ReplayClient
+type RecordingClient struct {
+	next   HTTPClient
+	dir    string
+	logger *logger.UPPLogger
+}
+
+// NewRecordingClient records the responses of next in dir, creating it if needed
+func NewRecordingClient(next HTTPClient, dir string, ftLogger *logger.UPPLogger) (*RecordingClient, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating record directory: %w", err)
+	}
+	return &RecordingClient{next: next, dir: dir, logger: ftLogger}, nil
+}
+
+func (c *RecordingClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.next.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	recording := Recording{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if json.Valid(body) {
+		recording.Body = body
+	} else {
+		recording.Text = string(body)
+	}
+	file := recordingFile(c.dir, req)
+	if err := writeRecording(file, recording); err != nil {
+		c.logger.WithError(err).WithField("url", recording.URL).Warn("failed to record upstream response")
+	}
+	return resp, nil
+}
+
+// writeRecording replaces the recording in file, never leaving it partly written
+func writeRecording(file string, recording Recording) error {
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".recording-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// ReplayClient answers requests only from the responses saved by a RecordingClient, failing with ErrNoRecording
+// for any other request. Good to go checks pass while the directory is readable.
+type ReplayClient struct {
+	dir    string
+	logger *logger.UPPLogger
+}
+
+// NewReplayClient replays the recordings in dir
+func NewReplayClient(dir string, ftLogger *logger.UPPLogger) (*ReplayClient, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("opening replay directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("replay directory %s is not a directory", dir)
+	}
+	return &ReplayClient{dir: dir, logger: ftLogger}, nil
+}
+
+func (c *ReplayClient) Do(req *http.Request) (*http.Response, error) {
+	file := recordingFile(c.dir, req)
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) && strings.HasSuffix(req.URL.Path, "/__gtg") {
+		if _, err := os.Stat(c.dir); err != nil {
+			return nil, err
+		}
+		return replayResponse(req, Recording{StatusCode: http.StatusOK, Text: "OK"}), nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		c.logger.WithField("url", req.URL.String()).WithField("file", file).Warn("no recording to replay, record it with --record-dir")
+		return nil, fmt.Errorf("%w of %s %s, expected in %s", ErrNoRecording, req.Method, req.URL.RequestURI(), file)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	recording := Recording{}
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("reading recording %s: %w", file, err)
+	}
+	return replayResponse(req, recording), nil
+}
+
+func replayResponse(req *http.Request, recording Recording) *http.Response {
+	body := recording.body()
+	header := http.Header{}
+	if recording.ContentType != "" {
+		header.Set("Content-Type", recording.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recording.StatusCode, http.StatusText(recording.StatusCode)),
+		StatusCode:    recording.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,78 @@
+package organisations
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	log := logger.NewUPPInfoLogger("tests")
+	dir := filepath.Join(t.TempDir(), "recordings")
+	get := func(client HTTPClient, url string) *httptest.ResponseRecorder {
+		router := mux.NewRouter()
+		h := NewHandler(client, "http://concepts.example.com", log)
+		h.RegisterHandlers(router)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		return rec
+	}
+
+	recorder, err := NewRecordingClient(&mockHTTPClient{resp: getCompleteOrganisationAsConcept, statusCode: 200}, dir, log)
+	require.NoError(t, err)
+	recorded := get(recorder, "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	require.Equal(t, http.StatusOK, recorded.Code)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Contains(t, files[0], "GET_concepts_7c5218a0-3755-463e-abbc-1a1632cfd1da-")
+
+	replayer, err := NewReplayClient(dir, log)
+	require.NoError(t, err)
+	replayed := get(replayer, "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	assert.Equal(t, http.StatusOK, replayed.Code)
+	assert.Equal(t, recorded.Body.String(), replayed.Body.String())
+
+	assert.Equal(t, http.StatusInternalServerError, get(replayer, "/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6").Code)
+
+	req, _ := http.NewRequest("GET", "http://localhost:8081/concepts/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", nil)
+	_, err = replayer.Do(req)
+	assert.True(t, errors.Is(err, ErrNoRecording))
+	assert.Contains(t, err.Error(), "/concepts/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6")
+
+	h := NewHandler(replayer, "http://concepts.example.com", log)
+	_, err = h.Checker()
+	assert.NoError(t, err, "good to go passes while the recordings can be read")
+}
+
+func TestRecordingNotFound(t *testing.T) {
+	log := logger.NewUPPInfoLogger("tests")
+	dir := t.TempDir()
+	recorder, err := NewRecordingClient(&mockHTTPClient{resp: "Not Found", statusCode: 404}, dir, log)
+	require.NoError(t, err)
+	req, _ := http.NewRequest("GET", "http://localhost:8081/concepts/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", nil)
+	_, err = recorder.Do(req)
+	require.NoError(t, err)
+
+	replayer, err := NewReplayClient(dir, log)
+	require.NoError(t, err)
+	resp, err := replayer.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	_, err = NewReplayClient(filepath.Join(dir, "missing"), log)
+	assert.Error(t, err)
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0600))
+	_, err = NewReplayClient(file, log)
+	assert.Error(t, err)
+}
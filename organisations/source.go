@@ -0,0 +1,251 @@
+package organisations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OrganisationSource provides the concepts organisations are transformed from
+type OrganisationSource interface {
+	// Concept returns the concept with the UUID, including its related concepts when related is true.
+	// found is false if the source does not know the UUID.
+	Concept(ctx context.Context, uuid string, transID string, related bool) (concept ConceptApiResponse, found bool, err error)
+	// Search returns the concepts with any of the identifier values from the authority
+	Search(ctx context.Context, authority string, values []string, transID string) ([]ConceptApiResponse, error)
+	// Check reports whether the source can be read
+	Check() (string, error)
+}
+
+// WithSource reads organisations from source instead of public-concepts-api
+func WithSource(source OrganisationSource) HandlerOption {
+	return func(h *OrganisationsHandler) {
+		h.source = source
+	}
+}
+
+// UpstreamError is returned when public-concepts-api answers with a status other than 200 OK or 404 Not Found
+type UpstreamError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("%s returned HTTP status %d", e.URL, e.StatusCode)
+}
+
+// ConceptsAPISource is an OrganisationSource reading public-concepts-api
+type ConceptsAPISource struct {
+	client      HTTPClient
+	conceptsURL string
+	logger      *logger.UPPLogger
+	metrics     *Metrics
+}
+
+// NewConceptsAPISource reads the public-concepts-api at conceptsURL with client, recording its latency in m
+func NewConceptsAPISource(client HTTPClient, conceptsURL string, ftLogger *logger.UPPLogger, m *Metrics) *ConceptsAPISource {
+	return &ConceptsAPISource{client: client, conceptsURL: conceptsURL, logger: ftLogger, metrics: m}
+}
+
+func (s *ConceptsAPISource) Concept(ctx context.Context, uuid string, transID string, related bool) (ConceptApiResponse, bool, error) {
+	query := ""
+	if related {
+		query = relatedQueryParam
+	}
+	concept := ConceptApiResponse{}
+	log := s.logger.WithTransactionID(transID).WithUUID(uuid)
+	found, err := s.get(ctx, s.conceptsURL+"/concepts/"+uuid+query, transID, log, &concept)
+	return concept, found, err
+}
+
+func (s *ConceptsAPISource) Search(ctx context.Context, authority string, values []string, transID string) ([]ConceptApiResponse, error) {
+	log := s.logger.WithTransactionID(transID).WithField("authority", authority)
+
+	params := url.Values{}
+	params.Set("authority", authority)
+	for _, v := range values {
+		params.Add("identifierValue", v)
+	}
+
+	searchResponse := ConceptSearchResponse{}
+	found, err := s.get(ctx, s.conceptsURL+"/concepts?"+params.Encode(), transID, log, &searchResponse)
+	if err != nil || !found {
+		return nil, err
+	}
+	return searchResponse.Concepts, nil
+}
+
+func (s *ConceptsAPISource) Check() (string, error) {
+	req, err := http.NewRequest("GET", s.conceptsURL+"/__gtg", nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Add("User-Agent", "UPP public-organisations-api")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("health check returned a non-200 HTTP status: %v", resp.StatusCode)
+	}
+	return "Public Concepts API is healthy", nil
+}
+
+// get decodes the JSON response of a GET to the concepts API into v, found is false on a 404
+func (s *ConceptsAPISource) get(ctx context.Context, reqURL string, transID string, log *logger.LogEntry, v interface{}) (found bool, err error) {
+	ctx, span := tracer.Start(ctx, "GET public-concepts-api", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attribute.String("http.url", reqURL)))
+	defer span.End()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+
+	if err != nil {
+		msg := fmt.Sprintf("failed to create request to %s", reqURL)
+		log.WithError(err).Error(msg)
+		return false, err
+	}
+
+	request.Header.Set("X-Request-Id", transID)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+	start := time.Now()
+	resp, err := s.client.Do(request)
+	if err != nil {
+		s.metrics.observeConceptsAPI("error", time.Since(start))
+		span.SetStatus(codes.Error, err.Error())
+		msg := fmt.Sprintf("request to %s was unsuccessful", reqURL)
+		log.WithError(err).Error(msg)
+		return false, err
+	}
+	defer resp.Body.Close()
+	s.metrics.observeConceptsAPI(strconv.Itoa(resp.StatusCode), time.Since(start))
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = &UpstreamError{URL: reqURL, StatusCode: resp.StatusCode}
+		span.SetStatus(codes.Error, err.Error())
+		log.WithError(err).Error("request to public-concepts-api was unsuccessful")
+		return false, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		msg := fmt.Sprintf("failed to read response body: %v", resp.Body)
+		log.WithError(err).Error(msg)
+		return false, err
+	}
+
+	if err = json.Unmarshal(body, v); err != nil {
+		msg := fmt.Sprintf("failed to unmarshal response body: %v", body)
+		log.WithError(err).Error(msg)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// MemoryOrganisationSource is an OrganisationSource holding its concepts in memory. Concepts are found by their
+// canonical UUID and by their alternate UPP identifiers, as in public-concepts-api.
+type MemoryOrganisationSource struct {
+	concepts map[string]ConceptApiResponse
+	byUUID   map[string]string
+	ordered  []string
+}
+
+// NewMemoryOrganisationSource creates a source holding the concepts
+func NewMemoryOrganisationSource(concepts ...ConceptApiResponse) *MemoryOrganisationSource {
+	s := &MemoryOrganisationSource{concepts: map[string]ConceptApiResponse{}, byUUID: map[string]string{}}
+	for _, concept := range concepts {
+		canonical := canonicalUUID(concept.ID)
+		if _, ok := s.concepts[canonical]; !ok {
+			s.ordered = append(s.ordered, canonical)
+		}
+		s.concepts[canonical] = concept
+		s.byUUID[canonical] = canonical
+		for _, identifier := range concept.Identifiers {
+			if identifier.Authority == uppAuthority {
+				s.byUUID[identifier.IdentifierValue] = canonical
+			}
+		}
+	}
+	return s
+}
+
+// LoadMemoryOrganisationSource creates a source holding the concepts in the JSON file at path, either a list of
+// concepts or a public-concepts-api search response
+func LoadMemoryOrganisationSource(path string) (*MemoryOrganisationSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading organisation source file: %w", err)
+	}
+	var concepts []ConceptApiResponse
+	if err := json.Unmarshal(data, &concepts); err != nil {
+		search := ConceptSearchResponse{}
+		if searchErr := json.Unmarshal(data, &search); searchErr != nil {
+			return nil, fmt.Errorf("parsing organisation source file %s: %w", path, err)
+		}
+		concepts = search.Concepts
+	}
+	return NewMemoryOrganisationSource(concepts...), nil
+}
+
+// UUIDs lists the canonical UUIDs of the concepts in the order they were added
+func (s *MemoryOrganisationSource) UUIDs() []string {
+	return append([]string(nil), s.ordered...)
+}
+
+func (s *MemoryOrganisationSource) Concept(ctx context.Context, uuid string, transID string, related bool) (ConceptApiResponse, bool, error) {
+	canonical, ok := s.byUUID[uuid]
+	if !ok {
+		return ConceptApiResponse{}, false, nil
+	}
+	concept := s.concepts[canonical]
+	if !related {
+		concept.Related = nil
+	}
+	return concept, true, nil
+}
+
+func (s *MemoryOrganisationSource) Search(ctx context.Context, authority string, values []string, transID string) ([]ConceptApiResponse, error) {
+	requested := map[string]bool{}
+	for _, v := range values {
+		requested[v] = true
+	}
+	concepts := []ConceptApiResponse{}
+	for _, canonical := range s.ordered {
+		concept := s.concepts[canonical]
+		matches := authority == leiAuthority && requested[concept.LeiCode]
+		for _, identifier := range concept.Identifiers {
+			matches = matches || (identifier.Authority == authority && requested[identifier.IdentifierValue])
+		}
+		if matches {
+			concept.Related = nil
+			concepts = append(concepts, concept)
+		}
+	}
+	return concepts, nil
+}
+
+func (s *MemoryOrganisationSource) Check() (string, error) {
+	return fmt.Sprintf("In-memory source holds %d concepts", len(s.concepts)), nil
+}
@@ -0,0 +1,129 @@
+package organisations
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMemorySource(t *testing.T, concepts ...string) *MemoryOrganisationSource {
+	var parsed []ConceptApiResponse
+	for _, concept := range concepts {
+		c := ConceptApiResponse{}
+		require.NoError(t, json.Unmarshal([]byte(concept), &c))
+		parsed = append(parsed, c)
+	}
+	return NewMemoryOrganisationSource(parsed...)
+}
+
+var getMergedOrganisationAsConcept = `{
+	"id": "http://www.ft.com/thing/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6",
+	"apiUrl": "http://api.ft.com/concepts/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6",
+	"type": "http://www.ft.com/ontology/organisation/Organisation",
+	"prefLabel": "Google Inc",
+	"identifiers": [
+		{
+			"authority": "http://api.ft.com/system/UPP",
+			"identifierValue": "2d3e16e0-61cb-4322-8aff-3b01c59f4daa"
+		},
+		{
+			"authority": "http://api.ft.com/system/FACTSET",
+			"identifierValue": "05N3DN-E"
+		}
+	]
+}`
+
+func TestMemoryOrganisationSource(t *testing.T) {
+	ctx := context.Background()
+	source := newTestMemorySource(t, getCompleteOrganisationAsConcept, getMergedOrganisationAsConcept)
+
+	concept, found, err := source.Concept(ctx, "7c5218a0-3755-463e-abbc-1a1632cfd1da", "tid_test", true)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Nintendo Co Ltd", concept.PrefLabel)
+	assert.NotEmpty(t, concept.Related)
+
+	concept, _, _ = source.Concept(ctx, "7c5218a0-3755-463e-abbc-1a1632cfd1da", "tid_test", false)
+	assert.Empty(t, concept.Related, "related concepts are only returned when asked for")
+
+	concept, found, _ = source.Concept(ctx, "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "tid_test", true)
+	assert.True(t, found, "concepts are found by their UPP identifiers")
+	assert.Equal(t, "http://www.ft.com/thing/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", concept.ID)
+
+	_, found, err = source.Concept(ctx, "f92a4ca4-84f9-11e8-8f42-da24cd01f044", "tid_test", true)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	concepts, err := source.Search(ctx, "http://api.ft.com/system/FACTSET", []string{"05N3DN-E", "XXXXXX-E"}, "tid_test")
+	require.NoError(t, err)
+	require.Len(t, concepts, 1)
+	assert.Equal(t, "Google Inc", concepts[0].PrefLabel)
+
+	concepts, _ = source.Search(ctx, leiAuthority, []string{"353800FEEXU6I9M0ZF27"}, "tid_test")
+	require.Len(t, concepts, 1, "the leiCode is searched as an LEI identifier")
+	assert.Equal(t, "Nintendo Co Ltd", concepts[0].PrefLabel)
+	assert.Empty(t, concepts[0].Related)
+
+	message, err := source.Check()
+	assert.NoError(t, err)
+	assert.Contains(t, message, "2 concepts")
+}
+
+func TestLoadMemoryOrganisationSource(t *testing.T) {
+	dir := t.TempDir()
+	searchFile := filepath.Join(dir, "search.json")
+	require.NoError(t, os.WriteFile(searchFile, []byte(getSearchResultAsConcepts), 0600))
+	listFile := filepath.Join(dir, "list.json")
+	require.NoError(t, os.WriteFile(listFile, []byte("["+getBasicOrganisationAsConcept+"]"), 0600))
+	invalidFile := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalidFile, []byte("nintendo"), 0600))
+
+	source, err := LoadMemoryOrganisationSource(searchFile)
+	require.NoError(t, err)
+	_, found, _ := source.Concept(context.Background(), "f92a4ca4-84f9-11e8-8f42-da24cd01f044", "tid_test", true)
+	assert.True(t, found)
+
+	source, err = LoadMemoryOrganisationSource(listFile)
+	require.NoError(t, err)
+	_, found, _ = source.Concept(context.Background(), "d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", "tid_test", true)
+	assert.True(t, found)
+
+	_, err = LoadMemoryOrganisationSource(invalidFile)
+	assert.Error(t, err)
+	_, err = LoadMemoryOrganisationSource(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestHandlersWithMemorySource(t *testing.T) {
+	source := newTestMemorySource(t, getCompleteOrganisationAsConcept, getMergedOrganisationAsConcept, getPersonAsConcept)
+	router := mux.NewRouter()
+	h := NewHandler(nil, "", logger.NewUPPInfoLogger("tests"), WithSource(source))
+	h.RegisterHandlers(router)
+
+	request := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		return rec
+	}
+
+	rec := request("/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, getTransformedCompleteOrganisation, rec.Body.String())
+
+	rec = request("/organisations/2d3e16e0-61cb-4322-8aff-3b01c59f4daa")
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", rec.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusNotFound, request("/organisations/f92a4ca4-84f9-11e8-8f42-da24cd01f044").Code)
+	assert.Equal(t, http.StatusNotFound, request("/organisations/a4ab4a5c-2b1e-4e1b-9b4c-0e9b4d1e7c11").Code)
+	assert.Equal(t, http.StatusMovedPermanently, request("/organisations?authority=FACTSET&identifierValue=05N3DN-E").Code)
+}
@@ -0,0 +1,142 @@
+package organisations
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/gorilla/mux"
+)
+
+const maxSubscribedUUIDs = 1000
+
+// RegisterWebhookHandlers exposes the webhook subscriptions under /webhooks to the callers the Authenticator
+// authenticated with scope
+func (h *OrganisationsHandler) RegisterWebhookHandlers(router *mux.Router, scope string) {
+	if h.webhooks == nil {
+		return
+	}
+	h.logger.Info("Registering webhook handlers")
+	authorised := func(handler http.HandlerFunc) http.Handler {
+		return requireScope(scope, handler)
+	}
+
+	router.Handle("/webhooks/subscriptions", methodHandler{
+		"GET":  authorised(h.GetSubscriptions),
+		"POST": authorised(h.CreateSubscription),
+	})
+	router.Handle("/webhooks/subscriptions/{id}", methodHandler{
+		"GET":    authorised(h.GetSubscription),
+		"DELETE": authorised(h.DeleteSubscription),
+	})
+	router.Handle("/webhooks/dead-letters", methodHandler{
+		"GET": authorised(h.GetDeadLetters),
+	})
+}
+
+// CreateSubscription registers a callback URL for changes to a set of organisations. The response is the only time
+// the secret signing the webhooks is returned.
+func (h *OrganisationsHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+	var sub Subscription
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, errorInvalidSubscription, "subscription is not valid JSON")
+		return
+	}
+	if err := validateSubscription(&sub); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, errorInvalidSubscription, err.Error())
+		return
+	}
+	callback, _ := url.Parse(sub.CallbackURL)
+	if err := h.webhooks.allowCallback(r.Context(), callback.Hostname()); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, errorInvalidSubscription, err.Error())
+		return
+	}
+	// Subscribers see the fields they could read from the API, whatever scopes they ask for
+	sub.Owner = callerSubject(r.Context())
+	sub.Scopes = callerScopes(r.Context())
+	sub, err := h.webhooks.Subscribe(sub)
+	if err != nil {
+		h.logger.WithTransactionID(transID).WithError(err).Error("failed to create webhook subscription")
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "failed to create subscription")
+		return
+	}
+	h.logger.WithTransactionID(transID).
+		WithField("subscription", sub.ID).
+		WithField("callbackURL", sub.CallbackURL).
+		WithField("uuids", len(sub.UUIDs)).
+		Info("created webhook subscription")
+	w.Header().Set("Location", "/webhooks/subscriptions/"+sub.ID)
+	writeAdminJSON(w, r, http.StatusCreated, sub)
+}
+
+// GetSubscriptions lists the subscriptions of the caller
+func (h *OrganisationsHandler) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, r, http.StatusOK, h.webhooks.Subscriptions(callerSubject(r.Context())))
+}
+
+// GetSubscription returns a subscription of the caller. The subscriptions of other callers are not found.
+func (h *OrganisationsHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.webhooks.Subscription(callerSubject(r.Context()), mux.Vars(r)["id"])
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, errorNotFound, "subscription not found")
+		return
+	}
+	writeAdminJSON(w, r, http.StatusOK, sub)
+}
+
+// DeleteSubscription stops the webhooks of a subscription of the caller
+func (h *OrganisationsHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+	ok, err := h.webhooks.Unsubscribe(callerSubject(r.Context()), id)
+	if err != nil {
+		h.logger.WithTransactionID(transID).WithField("subscription", id).WithError(err).Error("failed to delete webhook subscription")
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "failed to delete subscription")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, errorNotFound, "subscription not found")
+		return
+	}
+	h.logger.WithTransactionID(transID).
+		WithField("subscription", id).
+		Info("deleted webhook subscription")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeadLetters lists the webhooks of the subscriptions of the caller that could not be delivered, for one
+// subscription when the subscription parameter is set
+func (h *OrganisationsHandler) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := h.webhooks.DeadLetters(callerSubject(r.Context()), r.URL.Query().Get("subscription"))
+	if err != nil {
+		h.logger.WithTransactionID(transactionidutils.GetTransactionIDFromRequest(r)).WithError(err).Error("failed to read dead-lettered webhooks")
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "failed to return dead letters")
+		return
+	}
+	writeAdminJSON(w, r, http.StatusOK, letters)
+}
+
+// validateSubscription checks the callback URL and the UUIDs of a new subscription, normalising the UUIDs
+func validateSubscription(sub *Subscription) error {
+	callback, err := url.Parse(sub.CallbackURL)
+	if err != nil || (callback.Scheme != "http" && callback.Scheme != "https") || callback.Host == "" {
+		return fmt.Errorf("callbackURL must be an absolute http or https URL")
+	}
+	if len(sub.UUIDs) == 0 || len(sub.UUIDs) > maxSubscribedUUIDs {
+		return fmt.Errorf("uuids must list between 1 and %d organisations", maxSubscribedUUIDs)
+	}
+	set := map[string]bool{}
+	for _, uuid := range sub.UUIDs {
+		uuid = strings.ToLower(uuid)
+		if !wholeUUID.MatchString(uuid) {
+			return fmt.Errorf("uuid '%s' is invalid", uuid)
+		}
+		set[uuid] = true
+	}
+	sub.UUIDs = sortedKeys(set)
+	return nil
+}
@@ -0,0 +1,34 @@
+package organisations
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// transactionIDAttribute links spans to the FT transaction ID logged for the same request
+const transactionIDAttribute = "ft.transaction_id"
+
+var tracer = otel.Tracer("github.com/Financial-Times/public-organisations-api/v3/organisations")
+
+// observeRequest records how a request for an organisation was answered in the metrics and on its span
+func (h *OrganisationsHandler) observeRequest(span trace.Span, status int, outcome lookupOutcome) {
+	h.metrics.observeRequest(status, outcome)
+	span.SetAttributes(
+		attribute.String("organisation.outcome", string(outcome)),
+		attribute.Int("http.status_code", status),
+	)
+	if status >= 500 {
+		span.SetStatus(codes.Error, string(outcome))
+	}
+}
+
+func endSpan(span trace.Span, outcome lookupOutcome, err error) {
+	span.SetAttributes(attribute.String("organisation.outcome", string(outcome)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,70 @@
+package organisations
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type headerCapturingClient struct {
+	headers http.Header
+}
+
+func (c *headerCapturingClient) Do(req *http.Request) (*http.Response, error) {
+	c.headers = req.Header.Clone()
+	return &http.Response{Body: ioutil.NopCloser(bytes.NewReader([]byte(getBasicOrganisationAsConcept))), StatusCode: 200}, nil
+}
+
+func TestGetOrganisationIsTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	}()
+
+	client := &headerCapturingClient{}
+	router := mux.NewRouter()
+	bh := NewHandler(client, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"))
+	bh.RegisterHandlers(router)
+
+	req := httptest.NewRequest("GET", "/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", nil)
+	req.Header.Set("X-Request-Id", "tid_tracing")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 200, rec.Code)
+
+	assert.Equal(t, "tid_tracing", client.headers.Get("X-Request-Id"))
+	assert.NotEmpty(t, client.headers.Get("traceparent"), "outbound request should carry the W3C trace context")
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	assert.Contains(t, spans, "GET public-concepts-api")
+	assert.Contains(t, spans, "getOrganisationFromSource")
+	assert.Contains(t, spans, "transformOrganisation")
+	if assert.Contains(t, spans, "GetOrganisation") {
+		root := spans["GetOrganisation"]
+		assert.Equal(t, root.SpanContext().TraceID(), spans["GET public-concepts-api"].SpanContext().TraceID())
+		attributes := map[string]string{}
+		for _, kv := range root.Attributes() {
+			attributes[string(kv.Key)] = kv.Value.Emit()
+		}
+		assert.Equal(t, "tid_tracing", attributes[transactionIDAttribute])
+		assert.Equal(t, "found", attributes["organisation.outcome"])
+	}
+}
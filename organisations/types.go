@@ -0,0 +1,59 @@
+package organisations
+
+import (
+	"strings"
+)
+
+// TypeFilter decides which concept types are served as organisations. A concept is accepted when its
+// full type hierarchy contains Organisation, optionally narrowed by an allow list and a deny list.
+// Entries in both lists match any type in the hierarchy, so denying company/Company also denies its subtypes.
+type TypeFilter struct {
+	allowed map[string]bool
+	denied  map[string]bool
+}
+
+// NewTypeFilter builds a TypeFilter. Types may be given as full URIs or relative to the FT ontology,
+// e.g. "company/PublicCompany". An empty allow list accepts every subtype of Organisation.
+func NewTypeFilter(allowed []string, denied []string) TypeFilter {
+	return TypeFilter{
+		allowed: typeSet(allowed),
+		denied:  typeSet(denied),
+	}
+}
+
+// Accepts reports whether a concept with the given type hierarchy should be served as an organisation.
+func (f TypeFilter) Accepts(types []string) bool {
+	isOrganisation := false
+	isAllowed := len(f.allowed) == 0
+	for _, t := range types {
+		if f.denied[t] {
+			return false
+		}
+		if t == ontologyPrefix+organisationSuffix {
+			isOrganisation = true
+		}
+		if f.allowed[t] {
+			isAllowed = true
+		}
+	}
+	return isOrganisation && isAllowed
+}
+
+func typeSet(types []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, t := range types {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		set[normaliseType(t)] = true
+	}
+	return set
+}
+
+func normaliseType(t string) string {
+	if strings.HasPrefix(t, "http://") || strings.HasPrefix(t, "https://") {
+		return t
+	}
+	return ontologyPrefix + "/" + strings.TrimPrefix(t, "/")
+}
@@ -0,0 +1,44 @@
+package organisations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeFilter(t *testing.T) {
+	organisation := []string{
+		"http://www.ft.com/ontology/core/Thing",
+		"http://www.ft.com/ontology/concept/Concept",
+		"http://www.ft.com/ontology/organisation/Organisation",
+	}
+	publicCompany := append(organisation,
+		"http://www.ft.com/ontology/company/Company",
+		"http://www.ft.com/ontology/company/PublicCompany",
+	)
+	person := []string{
+		"http://www.ft.com/ontology/core/Thing",
+		"http://www.ft.com/ontology/concept/Concept",
+		"http://www.ft.com/ontology/person/Person",
+	}
+
+	testCases := []struct {
+		name     string
+		filter   TypeFilter
+		types    []string
+		expected bool
+	}{
+		{"organisation is accepted by default", NewTypeFilter(nil, nil), organisation, true},
+		{"subtype is accepted by default", NewTypeFilter(nil, nil), publicCompany, true},
+		{"non organisation is rejected", NewTypeFilter(nil, nil), person, false},
+		{"allowed supertype accepts subtype", NewTypeFilter([]string{"company/Company"}, nil), publicCompany, true},
+		{"type outside allow list is rejected", NewTypeFilter([]string{"company/Company"}, nil), organisation, false},
+		{"allow list cannot widen beyond organisations", NewTypeFilter([]string{"person/Person"}, nil), person, false},
+		{"denied supertype rejects subtype", NewTypeFilter(nil, []string{"http://www.ft.com/ontology/company/Company"}), publicCompany, false},
+		{"deny wins over allow", NewTypeFilter([]string{"company/PublicCompany"}, []string{"company/PublicCompany"}), publicCompany, false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, tc.filter.Accepts(tc.types), tc.name)
+	}
+}
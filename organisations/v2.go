@@ -0,0 +1,235 @@
+package organisations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	ontology "github.com/Financial-Times/cm-graph-ontology"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	apiV1 = "v1"
+	apiV2 = "v2"
+)
+
+// OrganisationV2 is the organisation returned by version 2 of the API. It lists every financial instrument the
+// organisation issued and all of its labels with their types. types is always included, for the organisation and
+// for every organisation and instrument it embeds, while other fields are omitted when they are empty.
+type OrganisationV2 struct {
+	Thing
+	Types                  []string                `json:"types"`
+	DirectType             string                  `json:"directType,omitempty"`
+	Labels                 []LabelDetail           `json:"labels,omitempty"`
+	CountryCode            string                  `json:"countryCode,omitempty"`
+	CountryOfIncorporation string                  `json:"countryOfIncorporation,omitempty"`
+	PostalCode             string                  `json:"postalCode,omitempty"`
+	YearFounded            int                     `json:"yearFounded,omitempty"`
+	LegalEntityIdentifier  string                  `json:"leiCode,omitempty"`
+	Parent                 *RelatedOrganisationV2  `json:"parentOrganisation,omitempty"`
+	Subsidiaries           []RelatedOrganisationV2 `json:"subsidiaries,omitempty"`
+	Instruments            []InstrumentV2          `json:"instruments,omitempty"`
+	IsDeprecated           bool                    `json:"isDeprecated,omitempty"`
+}
+
+// RelatedOrganisationV2 is a parent or subsidiary embedded in an OrganisationV2
+type RelatedOrganisationV2 struct {
+	Thing
+	Types      []string `json:"types"`
+	DirectType string   `json:"directType,omitempty"`
+}
+
+// InstrumentV2 is a financial instrument issued by an OrganisationV2
+type InstrumentV2 struct {
+	Thing
+	Types      []string `json:"types"`
+	DirectType string   `json:"directType,omitempty"`
+	FIGI       string   `json:"figi,omitempty"`
+}
+
+// registerV2Handlers registers the routes of version 2 of the API
+func (h *OrganisationsHandler) registerV2Handlers(router *mux.Router) {
+	path := "/v2/organisations/{uuid}"
+	router.Handle(path, methodHandler{
+		"GET": h.countVersion(apiV2, path, h.GetOrganisationV2),
+	})
+	router.HandleFunc(path, h.MethodNotAllowedHandler)
+}
+
+// countVersion counts the requests for a route of an API version, so consumers still using an old version can be found
+func (h *OrganisationsHandler) countVersion(version string, route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.metrics.versionRequests.WithLabelValues(version, route).Inc()
+		next(w, r)
+	}
+}
+
+// GetOrganisationV2 is version 2 of the public API. Organisations are read from the source on every request, the cache,
+// history and webhooks only apply to version 1.
+func (h *OrganisationsHandler) GetOrganisationV2(w http.ResponseWriter, r *http.Request) {
+	uuid := mux.Vars(r)["uuid"]
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+	ctx, span := tracer.Start(r.Context(), "GetOrganisationV2", trace.WithAttributes(
+		attribute.String("organisation.uuid", uuid),
+		attribute.String(transactionIDAttribute, transID),
+	))
+	defer span.End()
+
+	format, ok := h.negotiateFormat(w, r)
+	if !ok {
+		h.observeRequest(span, http.StatusNotAcceptable, outcomeNotAcceptable)
+		writeProblem(w, r, http.StatusNotAcceptable, errorNotAcceptable, notAcceptableMessage(h.formats))
+		return
+	}
+	if canonicalUUID(uuid) != uuid {
+		h.logger.WithTransactionID(transID).WithUUID(uuid).Error("uuid is either missing or invalid")
+		h.observeRequest(span, http.StatusBadRequest, outcomeInvalidUUID)
+		writeProblem(w, r, http.StatusBadRequest, errorInvalidUUID, fmt.Sprintf("uuid '%s' is either missing or invalid", uuid))
+		return
+	}
+
+	organisation, outcome, err := h.getOrganisationV2FromSource(ctx, uuid, transID)
+	if err != nil {
+		h.observeRequest(span, http.StatusInternalServerError, outcome)
+		writeUpstreamProblem(w, r, "failed to return organisation", err)
+		return
+	}
+	if outcome != outcomeFound {
+		h.observeRequest(span, http.StatusNotFound, outcome)
+		writeProblem(w, r, http.StatusNotFound, errorNotFound, "organisation not found")
+		return
+	}
+	if redirectToCanonical(w, r, uuid, organisation.ID) {
+		h.observeRequest(span, http.StatusMovedPermanently, outcomeRedirect)
+		return
+	}
+	if !h.meetsContract(h.contractV2, uuid, organisation, transID) {
+		h.observeRequest(span, http.StatusInternalServerError, outcomeContractViolation)
+		writeProblem(w, r, http.StatusInternalServerError, errorContractViolation, "organisation does not match the API contract")
+		return
+	}
+	h.redact(w, r, &organisation)
+
+	w.Header().Set("Cache-Control", cacheControl())
+	w.Header().Set("Surrogate-Key", surrogateKeysV2(organisation))
+	if err = writeEncoded(w, format, http.StatusOK, organisation); err != nil {
+		h.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to encode organisation")
+		h.observeRequest(span, http.StatusInternalServerError, outcomeEncodingError)
+		writeProblem(w, r, http.StatusInternalServerError, errorInternal, "organisation could not be encoded")
+		return
+	}
+	h.observeRequest(span, http.StatusOK, outcomeFound)
+}
+
+func (h *OrganisationsHandler) getOrganisationV2FromSource(ctx context.Context, uuid string, transID string) (organisation OrganisationV2, outcome lookupOutcome, err error) {
+	ctx, span := tracer.Start(ctx, "getOrganisationV2FromSource", trace.WithAttributes(attribute.String("organisation.uuid", uuid)))
+	defer func() {
+		endSpan(span, outcome, err)
+	}()
+
+	concept, types, outcome, err := h.organisationConcept(ctx, uuid, transID)
+	if outcome != outcomeFound {
+		return OrganisationV2{}, outcome, err
+	}
+
+	_, transformSpan := tracer.Start(ctx, "transformOrganisationV2")
+	start := time.Now()
+	org, err := transformOrganisationV2(concept, types)
+	h.metrics.transformDuration.Observe(time.Since(start).Seconds())
+	transformSpan.End()
+	if err != nil {
+		h.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to transform organisation")
+		return OrganisationV2{}, outcomeUpstreamError, err
+	}
+	h.metrics.observeUnknowns(concept)
+
+	return org, outcomeFound, nil
+}
+
+// transformOrganisationV2 converts a concept with the given type hierarchy to version 2 of the organisation model
+func transformOrganisationV2(concept ConceptApiResponse, types []string) (OrganisationV2, error) {
+	org := OrganisationV2{
+		Thing: Thing{
+			ID:        convertID(concept.ID),
+			APIURL:    convertApiUrl(concept.ApiURL, "organisations"),
+			PrefLabel: concept.PrefLabel,
+		},
+		Types:                  types,
+		DirectType:             concept.Type,
+		CountryCode:            concept.CountryCode,
+		CountryOfIncorporation: concept.CountryOfIncorporation,
+		PostalCode:             concept.PostalCode,
+		YearFounded:            concept.YearFounded,
+		LegalEntityIdentifier:  concept.LeiCode,
+		IsDeprecated:           concept.IsDeprecated,
+	}
+
+	seen := map[LabelDetail]bool{}
+	for _, label := range transformLabelDetails(concept.AlternativeLabels) {
+		if !seen[label] {
+			seen[label] = true
+			org.Labels = append(org.Labels, label)
+		}
+	}
+
+	for _, item := range concept.Related {
+		c := item.Concept
+		types, err := ontology.FullTypeHierarchy(c.Type)
+		if err != nil {
+			return OrganisationV2{}, fmt.Errorf("getting type hierarchy for related concept %s of type %s: %w", c.ID, c.Type, err)
+		}
+
+		switch strings.TrimPrefix(item.Predicate, ontologyPrefix) {
+		case hasParentPredicate:
+			parent := relatedOrganisationV2(c, types)
+			org.Parent = &parent
+		case isParentPredicate:
+			org.Subsidiaries = append(org.Subsidiaries, relatedOrganisationV2(c, types))
+		case issuedPredicate:
+			org.Instruments = append(org.Instruments, InstrumentV2{
+				Thing: Thing{
+					ID:        convertID(c.ID),
+					APIURL:    convertApiUrl(c.ApiURL, "things"),
+					PrefLabel: c.PrefLabel,
+				},
+				Types:      types,
+				DirectType: c.Type,
+				FIGI:       c.Figi,
+			})
+		}
+	}
+	return org, nil
+}
+
+func relatedOrganisationV2(c Concept, types []string) RelatedOrganisationV2 {
+	return RelatedOrganisationV2{
+		Thing: Thing{
+			ID:        convertID(c.ID),
+			APIURL:    convertApiUrl(c.ApiURL, "organisations"),
+			PrefLabel: c.PrefLabel,
+		},
+		Types:      types,
+		DirectType: c.Type,
+	}
+}
+
+// surrogateKeysV2 are the surrogate keys of a version 2 response, as surrogateKeys are for version 1
+func surrogateKeysV2(org OrganisationV2) string {
+	keys := map[string]bool{allOrganisationsSurrogateKey: true, surrogateKey(canonicalUUID(org.ID)): true}
+	if org.Parent != nil {
+		keys[surrogateKey(canonicalUUID(org.Parent.ID))] = true
+	}
+	for _, subsidiary := range org.Subsidiaries {
+		keys[surrogateKey(canonicalUUID(subsidiary.ID))] = true
+	}
+	for _, instrument := range org.Instruments {
+		keys[surrogateKey(canonicalUUID(instrument.ID))] = true
+	}
+	return strings.Join(sortedKeys(keys), " ")
+}
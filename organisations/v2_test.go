@@ -0,0 +1,173 @@
+package organisations
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var getTransformedCompleteOrganisationV2 = `{
+	"id": "http://api.ft.com/things/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+	"apiUrl": "http://api.ft.com/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da",
+	"prefLabel": "Nintendo Co Ltd",
+	"types": [
+		"http://www.ft.com/ontology/core/Thing",
+		"http://www.ft.com/ontology/concept/Concept",
+		"http://www.ft.com/ontology/organisation/Organisation"
+	],
+	"directType": "http://www.ft.com/ontology/organisation/Organisation",
+	"labels": [
+		{"type": "formerName", "value": "Nintendo Playing Card Co., Ltd."},
+		{"type": "properName", "value": "Nintendo Co., Ltd."},
+		{"type": "shortName", "value": "Nintendo"}
+	],
+	"countryCode": "JP",
+	"countryOfIncorporation": "JP",
+	"postalCode": "601-8116",
+	"yearFounded": 1889,
+	"leiCode": "353800FEEXU6I9M0ZF27",
+	"parentOrganisation": {
+		"id": "http://api.ft.com/things/335e9e5a-8f2e-11e8-8f42-da24cd01f044",
+		"apiUrl": "http://api.ft.com/organisations/335e9e5a-8f2e-11e8-8f42-da24cd01f044",
+		"prefLabel": "Alphabet Inc",
+		"types": [
+			"http://www.ft.com/ontology/core/Thing",
+			"http://www.ft.com/ontology/concept/Concept",
+			"http://www.ft.com/ontology/organisation/Organisation"
+		],
+		"directType": "http://www.ft.com/ontology/organisation/Organisation"
+	},
+	"subsidiaries": [{
+		"id": "http://api.ft.com/things/1b070fbb-6331-3225-bb57-9108deb67df4",
+		"apiUrl": "http://api.ft.com/organisations/1b070fbb-6331-3225-bb57-9108deb67df4",
+		"prefLabel": "Nintendo France SARL",
+		"types": [
+			"http://www.ft.com/ontology/core/Thing",
+			"http://www.ft.com/ontology/concept/Concept",
+			"http://www.ft.com/ontology/organisation/Organisation"
+		],
+		"directType": "http://www.ft.com/ontology/organisation/Organisation"
+	}],
+	"instruments": [{
+		"id": "http://api.ft.com/things/dfee4b8f-ceee-37ba-ab24-752cf7a9281c",
+		"apiUrl": "http://api.ft.com/things/dfee4b8f-ceee-37ba-ab24-752cf7a9281c",
+		"prefLabel": "Nintendo Co., Ltd.",
+		"types": [
+			"http://www.ft.com/ontology/core/Thing",
+			"http://www.ft.com/ontology/concept/Concept",
+			"http://www.ft.com/ontology/FinancialInstrument"
+		],
+		"directType": "http://www.ft.com/ontology/FinancialInstrument",
+		"figi": "BBG000BLCPP4"
+	}, {
+		"id": "http://api.ft.com/things/0b5d2a3c-1d7e-4e8f-9a0b-1c2d3e4f5a6b",
+		"apiUrl": "http://api.ft.com/things/0b5d2a3c-1d7e-4e8f-9a0b-1c2d3e4f5a6b",
+		"prefLabel": "Nintendo ADR",
+		"types": [
+			"http://www.ft.com/ontology/core/Thing",
+			"http://www.ft.com/ontology/concept/Concept",
+			"http://www.ft.com/ontology/FinancialInstrument"
+		],
+		"directType": "http://www.ft.com/ontology/FinancialInstrument",
+		"figi": "BBG000BLDJ42"
+	}]
+}`
+
+// completeOrganisationWithInstruments is the complete organisation having issued a second instrument
+func completeOrganisationWithInstruments(t *testing.T) ConceptApiResponse {
+	concept := ConceptApiResponse{}
+	require.NoError(t, json.Unmarshal([]byte(getCompleteOrganisationAsConcept), &concept))
+	concept.Related = append(concept.Related, RelatedConcept{
+		Concept: Concept{
+			ID:        "http://api.ft.com/things/0b5d2a3c-1d7e-4e8f-9a0b-1c2d3e4f5a6b",
+			ApiURL:    "http://api.ft.com/concepts/0b5d2a3c-1d7e-4e8f-9a0b-1c2d3e4f5a6b",
+			Type:      "http://www.ft.com/ontology/FinancialInstrument",
+			PrefLabel: "Nintendo ADR",
+			Figi:      "BBG000BLDJ42",
+		},
+		Predicate: "http://www.ft.com/ontology/issued",
+	})
+	concept.AlternativeLabels = append(concept.AlternativeLabels, concept.AlternativeLabels[2])
+	return concept
+}
+
+func TestTransformOrganisationV2(t *testing.T) {
+	concept := completeOrganisationWithInstruments(t)
+	org, err := transformOrganisationV2(concept, []string{
+		"http://www.ft.com/ontology/core/Thing",
+		"http://www.ft.com/ontology/concept/Concept",
+		"http://www.ft.com/ontology/organisation/Organisation",
+	})
+	require.NoError(t, err)
+	actual, err := json.Marshal(org)
+	require.NoError(t, err)
+	assert.JSONEq(t, getTransformedCompleteOrganisationV2, string(actual))
+
+	org, err = transformOrganisationV2(ConceptApiResponse{Concept: Concept{ID: "http://www.ft.com/thing/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6"}}, []string{})
+	require.NoError(t, err)
+	actual, err = json.Marshal(org)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": "http://api.ft.com/things/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", "apiUrl": "", "types": []}`, string(actual))
+}
+
+func TestGetOrganisationV2(t *testing.T) {
+	concept, err := json.Marshal(completeOrganisationWithInstruments(t))
+	require.NoError(t, err)
+	source := newTestMemorySource(t, string(concept), getMergedOrganisationAsConcept)
+	metrics := NewMetrics(prometheus.NewRegistry())
+	h := NewHandler(nil, "", logger.NewUPPInfoLogger("tests"), WithSource(source), WithMetrics(metrics))
+	router := mux.NewRouter()
+	h.RegisterHandlers(router)
+
+	for _, test := range []struct {
+		name         string
+		method       string
+		url          string
+		expectedCode int
+		expectedBody string
+	}{
+		{"found", "GET", "/v2/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", http.StatusOK, getTransformedCompleteOrganisationV2},
+		{"invalid uuid", "GET", "/v2/organisations/1234", http.StatusBadRequest,
+			`{"type": "about:blank", "title": "Bad Request", "status": 400, "code": "invalid-uuid", "detail": "uuid '1234' is either missing or invalid", "transactionId": "tid_test"}`},
+		{"not found", "GET", "/v2/organisations/f92a4ca4-84f9-11e8-8f42-da24cd01f044", http.StatusNotFound,
+			`{"type": "about:blank", "title": "Not Found", "status": 404, "code": "not-found", "detail": "organisation not found", "transactionId": "tid_test"}`},
+		{"method not allowed", "DELETE", "/v2/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", http.StatusMethodNotAllowed,
+			`{"type": "about:blank", "title": "Method Not Allowed", "status": 405, "code": "method-not-allowed", "detail": "method DELETE is not allowed", "transactionId": "tid_test"}`},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.url, nil)
+		req.Header.Set("X-Request-Id", "tid_test")
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, test.expectedCode, rec.Code, test.name)
+		if test.expectedCode == http.StatusOK {
+			assert.Equal(t, jsonMediaType, rec.Header().Get("Content-Type"), test.name)
+		} else {
+			assert.Equal(t, problemMediaType, rec.Header().Get("Content-Type"), test.name)
+		}
+		assert.JSONEq(t, test.expectedBody, rec.Body.String(), test.name)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v2/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", nil))
+	assert.Equal(t, "organisation-0b5d2a3c-1d7e-4e8f-9a0b-1c2d3e4f5a6b organisation-1b070fbb-6331-3225-bb57-9108deb67df4 "+
+		"organisation-335e9e5a-8f2e-11e8-8f42-da24cd01f044 organisation-7c5218a0-3755-463e-abbc-1a1632cfd1da "+
+		"organisation-dfee4b8f-ceee-37ba-ab24-752cf7a9281c organisations", rec.Header().Get("Surrogate-Key"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v2/organisations/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", nil))
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/v2/organisations/d6b12f0c-bf3f-4045-a07b-1e4e49103fd6", rec.Header().Get("Location"))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/organisations/7c5218a0-3755-463e-abbc-1a1632cfd1da", nil))
+	assert.Equal(t, 5.0, testutil.ToFloat64(metrics.versionRequests.WithLabelValues(apiV2, "/v2/organisations/{uuid}")),
+		"requests for methods that are not allowed are not counted")
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.versionRequests.WithLabelValues(apiV1, "/organisations/{uuid}")))
+}
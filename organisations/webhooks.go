@@ -0,0 +1,470 @@
+package organisations
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	webhookEvent           = "organisation.changed"
+	webhookQueueSize       = 1000
+	maxDeadLetters         = 1000
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookIDHeader        = "X-Webhook-Id"
+)
+
+// WebhookConfig describes how changes are detected and webhooks delivered
+type WebhookConfig struct {
+	// PollInterval is how often subscribed organisations are read to detect changes. Zero only detects changes
+	// in the organisations the service reads anyway.
+	PollInterval time.Duration
+	// MaxAttempts is how many times a webhook is sent before it is dead-lettered
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubling for each retry up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// CallbackHosts are the hosts callback URLs may name, *.example.com naming every subdomain of example.com.
+	// When empty callback URLs may name any host but those at internal addresses.
+	CallbackHosts []string
+}
+
+// Webhooks keeps the subscriptions to organisation changes and delivers a signed webhook with the changed fields
+// to each subscriber when an organisation it subscribed to changes. The state is saved in a WebhookStore, and the
+// subscriptions also kept in memory as every organisation read is checked against them.
+type Webhooks struct {
+	client     HTTPClient
+	store      WebhookStore
+	auth       *Authenticator
+	cfg        WebhookConfig
+	logger     *logger.UPPLogger
+	deliveries *prometheus.CounterVec
+	now        func() time.Time
+	sleep      func(ctx context.Context, d time.Duration) bool
+	lookupIP   func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+	mu            sync.Mutex
+	subscriptions map[string]Subscription
+
+	pending chan delivery
+	polls   chan []string
+}
+
+type delivery struct {
+	subscription Subscription
+	payload      WebhookPayload
+	transID      string
+}
+
+// NewWebhooks loads the subscriptions saved in store, delivering webhooks with client
+func NewWebhooks(client HTTPClient, store WebhookStore, cfg WebhookConfig, ftLogger *logger.UPPLogger, registerer prometheus.Registerer) (*Webhooks, error) {
+	subs, err := store.Subscriptions()
+	if err != nil {
+		return nil, fmt.Errorf("loading webhook subscriptions: %w", err)
+	}
+	w := &Webhooks{
+		client: client,
+		store:  store,
+		cfg:    cfg,
+		logger: ftLogger,
+		deliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Attempts to deliver webhooks by result: delivered, retried or dead-lettered.",
+		}, []string{"result"}),
+		now:           time.Now,
+		sleep:         sleepContext,
+		lookupIP:      net.DefaultResolver.LookupIPAddr,
+		subscriptions: map[string]Subscription{},
+		pending:       make(chan delivery, webhookQueueSize),
+		polls:         make(chan []string, 1),
+	}
+	for _, sub := range subs {
+		w.subscriptions[sub.ID] = sub
+	}
+	registerer.MustRegister(w.deliveries)
+	return w, nil
+}
+
+// WithWebhooks notifies the subscribers of w when an organisation the handler reads has changed
+func WithWebhooks(w *Webhooks) HandlerOption {
+	return func(h *OrganisationsHandler) {
+		h.webhooks = w
+	}
+}
+
+// Subscribe registers the subscription, generating its ID and, unless it has one, its secret
+func (w *Webhooks) Subscribe(sub Subscription) (Subscription, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if sub.Secret == "" {
+		if sub.Secret, err = randomHex(32); err != nil {
+			return Subscription{}, err
+		}
+	}
+	sub.ID = id
+	sub.CreatedAt = w.now().UTC()
+
+	w.mu.Lock()
+	err = w.store.SaveSubscription(sub)
+	if err == nil {
+		w.subscriptions[id] = sub
+	}
+	w.mu.Unlock()
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	// Read the new organisations straight away, so changes are detected from now on
+	select {
+	case w.polls <- sub.UUIDs:
+	default:
+	}
+	return sub, nil
+}
+
+// Unsubscribe removes the subscription of owner, and reports whether it existed. The versions seen of the
+// organisations no other subscription is to are forgotten.
+func (w *Webhooks) Unsubscribe(owner string, id string) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sub, ok := w.subscriptions[id]
+	if !ok || sub.Owner != owner {
+		return false, nil
+	}
+	if err := w.store.DeleteSubscription(id); err != nil {
+		return false, err
+	}
+	delete(w.subscriptions, id)
+	for _, uuid := range sub.UUIDs {
+		if len(w.subscribedTo(uuid)) > 0 {
+			continue
+		}
+		if err := w.store.DeleteLastSeen(uuid); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// Subscription returns the subscription of owner with the ID, without its secret
+func (w *Webhooks) Subscription(owner string, id string) (Subscription, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sub, ok := w.subscriptions[id]
+	if !ok || sub.Owner != owner {
+		return Subscription{}, false
+	}
+	sub.Secret = ""
+	return sub, true
+}
+
+// Subscriptions returns the subscriptions of owner, oldest first, without their secrets
+func (w *Webhooks) Subscriptions(owner string) []Subscription {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	subs := []Subscription{}
+	for _, sub := range w.subscriptions {
+		if sub.Owner != owner {
+			continue
+		}
+		sub.Secret = ""
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].ID < subs[j].ID
+		}
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+	return subs
+}
+
+// DeadLetters returns the webhooks of the subscriptions of owner that could not be delivered, oldest first, for
+// every one of them when subscriptionID is empty. Only the latest ones are kept.
+func (w *Webhooks) DeadLetters(owner string, subscriptionID string) ([]DeadLetter, error) {
+	saved, err := w.store.DeadLetters()
+	if err != nil {
+		return nil, err
+	}
+	owned := map[string]bool{}
+	for _, sub := range w.Subscriptions(owner) {
+		owned[sub.ID] = true
+	}
+	letters := []DeadLetter{}
+	for _, letter := range saved {
+		id := letter.Payload.SubscriptionID
+		if owned[id] && (subscriptionID == "" || id == subscriptionID) {
+			letters = append(letters, letter)
+		}
+	}
+	return letters, nil
+}
+
+// Observe compares the organisation with the version seen before and, if it changed, queues a webhook for each
+// subscription to it. The first version seen of an organisation is what later versions are compared with.
+func (w *Webhooks) Observe(org Organisation, transID string) {
+	uuid := canonicalUUID(org.ID)
+	fields, err := organisationFields(org)
+	if err != nil {
+		w.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to compare organisation for webhooks")
+		return
+	}
+
+	w.mu.Lock()
+	subs := w.subscribedTo(uuid)
+	if len(subs) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	previous, seen, err := w.store.LastSeen(uuid)
+	var changes []FieldChange
+	if err == nil && seen {
+		changes = diffFields(previous, fields)
+	}
+	// Only save versions that changed, most reads of an organisation find it as it was
+	if err == nil && (!seen || len(changes) > 0) {
+		err = w.store.SetLastSeen(uuid, fields)
+	}
+	w.mu.Unlock()
+	if err != nil {
+		w.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to compare organisation for webhooks")
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	for _, sub := range subs {
+		id, err := randomHex(16)
+		if err != nil {
+			w.logger.WithTransactionID(transID).WithUUID(uuid).WithError(err).Error("failed to create webhook")
+			continue
+		}
+		d := delivery{subscription: sub, transID: transID, payload: WebhookPayload{
+			ID:             id,
+			Event:          webhookEvent,
+			SubscriptionID: sub.ID,
+			UUID:           uuid,
+			DetectedAt:     w.now().UTC(),
+			Changes:        changes,
+			Organisation:   org,
+		}}
+		if !w.auth.redactWebhook(&d.payload, sub.Scopes) {
+			// Only fields the subscriber is not entitled to changed
+			continue
+		}
+		select {
+		case w.pending <- d:
+		default:
+			w.deadLetter(d, 0, 0, "webhook queue is full")
+		}
+	}
+}
+
+func (h *OrganisationsHandler) notifyWebhooks(org Organisation, transID string) {
+	if h.webhooks != nil {
+		h.webhooks.Observe(org, transID)
+	}
+}
+
+// subscribedTo returns the subscriptions to the organisation, and must be called holding mu
+func (w *Webhooks) subscribedTo(uuid string) []Subscription {
+	var subs []Subscription
+	for _, sub := range w.subscriptions {
+		for _, subscribed := range sub.UUIDs {
+			if subscribed == uuid {
+				subs = append(subs, sub)
+				break
+			}
+		}
+	}
+	return subs
+}
+
+func (w *Webhooks) subscribedUUIDs() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	set := map[string]bool{}
+	for _, sub := range w.subscriptions {
+		for _, uuid := range sub.UUIDs {
+			set[uuid] = true
+		}
+	}
+	return sortedKeys(set)
+}
+
+// Run delivers the queued webhooks, and reads the subscribed organisations through the handler every poll
+// interval, until ctx is done
+func (w *Webhooks) Run(ctx context.Context, h *OrganisationsHandler) {
+	var tick <-chan time.Time
+	if w.cfg.PollInterval > 0 {
+		ticker := time.NewTicker(w.cfg.PollInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-w.pending:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w.deliver(ctx, d)
+			}()
+		case uuids := <-w.polls:
+			w.poll(ctx, h, uuids)
+		case <-tick:
+			w.poll(ctx, h, w.subscribedUUIDs())
+		}
+	}
+}
+
+func (w *Webhooks) poll(ctx context.Context, h *OrganisationsHandler, uuids []string) {
+	transID := transactionidutils.NewTransactionID()
+	for _, uuid := range uuids {
+		if ctx.Err() != nil {
+			return
+		}
+		org, outcome, err := h.getOrganisationFromSource(ctx, uuid, transID)
+		if err != nil || outcome != outcomeFound {
+			continue
+		}
+		w.Observe(org, transID)
+	}
+}
+
+// deliver sends the webhook until the subscriber accepts it, backing off between attempts, and dead-letters it
+// once the attempts run out or the subscriber rejects it
+func (w *Webhooks) deliver(ctx context.Context, d delivery) {
+	body, err := json.Marshal(d.payload)
+	if err != nil {
+		w.deadLetter(d, 0, 0, err.Error())
+		return
+	}
+	backoff := w.cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		status, err := w.send(ctx, d, body)
+		if err == nil {
+			w.deliveries.WithLabelValues("delivered").Inc()
+			w.logger.WithTransactionID(d.transID).WithUUID(d.payload.UUID).
+				WithField("subscription", d.subscription.ID).
+				WithField("attempts", attempt).
+				Info("delivered webhook")
+			return
+		}
+		if attempt >= w.cfg.MaxAttempts || !retryable(status) {
+			w.deadLetter(d, attempt, status, err.Error())
+			return
+		}
+		w.deliveries.WithLabelValues("retried").Inc()
+		if !w.sleep(ctx, backoff) {
+			w.deadLetter(d, attempt, status, "service shut down before the webhook was delivered")
+			return
+		}
+		backoff *= 2
+		if backoff > w.cfg.MaxBackoff {
+			backoff = w.cfg.MaxBackoff
+		}
+	}
+}
+
+func (w *Webhooks) send(ctx context.Context, d delivery, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.subscription.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(w.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "UPP public-organisations-api")
+	req.Header.Set(transactionidutils.TransactionIDHeader, d.transID)
+	req.Header.Set(webhookIDHeader, d.payload.ID)
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, SignWebhook(d.subscription.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (w *Webhooks) deadLetter(d delivery, attempts int, status int, reason string) {
+	w.deliveries.WithLabelValues("dead-lettered").Inc()
+	w.logger.WithTransactionID(d.transID).WithUUID(d.payload.UUID).
+		WithField("subscription", d.subscription.ID).
+		WithField("attempts", attempts).
+		WithField("reason", reason).
+		Warn("dead-lettered webhook")
+
+	err := w.store.AddDeadLetter(DeadLetter{
+		CallbackURL: d.subscription.CallbackURL,
+		Attempts:    attempts,
+		LastStatus:  status,
+		LastError:   reason,
+		FailedAt:    w.now().UTC(),
+		Payload:     d.payload,
+	}, maxDeadLetters)
+	if err != nil {
+		w.logger.WithTransactionID(d.transID).WithUUID(d.payload.UUID).WithError(err).Error("failed to save dead-lettered webhook")
+	}
+}
+
+// retryable reports whether a failed delivery is worth sending again: subscribers rejecting a webhook with a client
+// error will reject it again, unless they timed out or throttled it
+func retryable(status int) bool {
+	return status < 400 || status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
+// SignWebhook returns the X-Webhook-Signature of a webhook: the hex HMAC-SHA256, keyed with the subscription
+// secret, of the X-Webhook-Timestamp, a dot and the body
+func SignWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
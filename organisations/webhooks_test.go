@@ -0,0 +1,351 @@
+package organisations
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWebhooks(t *testing.T, maxAttempts int) (*Webhooks, *OrganisationsHandler) {
+	webhooks, err := NewWebhooks(http.DefaultClient, NewMemoryWebhookStore(), WebhookConfig{MaxAttempts: maxAttempts, InitialBackoff: time.Second, MaxBackoff: time.Minute},
+		logger.NewUPPInfoLogger("tests"), prometheus.NewRegistry())
+	require.NoError(t, err)
+	webhooks.sleep = func(ctx context.Context, d time.Duration) bool { return true }
+	webhooks.lookupIP = testLookupIP
+	h := NewHandler(&mockHTTPClient{statusCode: 404}, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"), WithWebhooks(webhooks))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go webhooks.Run(ctx, &h)
+	return webhooks, &h
+}
+
+// testLookupIP resolves example.com to a public address and its internal subdomain to a private one
+func testLookupIP(ctx context.Context, host string) ([]net.IPAddr, error) {
+	switch host {
+	case "example.com":
+		return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}}, nil
+	case "internal.example.com":
+		return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}, {IP: net.ParseIP("10.0.0.1")}}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestWebhooksDeliverSignedChanges(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	webhooks, _ := newTestWebhooks(t, 3)
+	sub, err := webhooks.Subscribe(Subscription{CallbackURL: server.URL, UUIDs: []string{"7c5218a0-3755-463e-abbc-1a1632cfd1da"}})
+	require.NoError(t, err)
+	assert.NotEmpty(t, sub.Secret)
+
+	org := testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	webhooks.Observe(org, "tid_first")
+	webhooks.Observe(org, "tid_unchanged")
+	org.PrefLabel = "Nintendo"
+	webhooks.Observe(org, "tid_changed")
+
+	var req *http.Request
+	select {
+	case req = <-received:
+	case <-time.After(time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	body := <-bodies
+	assert.Equal(t, "tid_changed", req.Header.Get("X-Request-Id"))
+	assert.Equal(t, SignWebhook(sub.Secret, req.Header.Get(webhookTimestampHeader), body), req.Header.Get(webhookSignatureHeader))
+
+	var payload WebhookPayload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, webhookEvent, payload.Event)
+	assert.Equal(t, sub.ID, payload.SubscriptionID)
+	assert.Equal(t, req.Header.Get(webhookIDHeader), payload.ID)
+	assert.Equal(t, []FieldChange{{Field: "prefLabel", Previous: "Nintendo Co Ltd", Current: "Nintendo"}}, payload.Changes)
+
+	select {
+	case <-received:
+		t.Fatal("only changed organisations should be delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWebhooksRetryThenDeadLetter(t *testing.T) {
+	for name, test := range map[string]struct {
+		status           int
+		expectedAttempts int
+	}{
+		"server error is retried":     {http.StatusServiceUnavailable, 3},
+		"throttling is retried":       {http.StatusTooManyRequests, 3},
+		"client error is not retried": {http.StatusGone, 1},
+	} {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(test.status)
+		}))
+
+		webhooks, _ := newTestWebhooks(t, 3)
+		sub, err := webhooks.Subscribe(Subscription{CallbackURL: server.URL, UUIDs: []string{"7c5218a0-3755-463e-abbc-1a1632cfd1da"}})
+		require.NoError(t, err)
+		org := testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+		webhooks.Observe(org, "tid_first")
+		org.PrefLabel = "Nintendo"
+		webhooks.Observe(org, "tid_changed")
+
+		require.Eventually(t, func() bool {
+			letters, err := webhooks.DeadLetters("", "")
+			return err == nil && len(letters) == 1
+		}, time.Second, time.Millisecond, name)
+		letters, err := webhooks.DeadLetters("", sub.ID)
+		require.NoError(t, err, name)
+		letter := letters[0]
+		assert.Equal(t, test.expectedAttempts, letter.Attempts, name)
+		assert.Equal(t, test.status, letter.LastStatus, name)
+		assert.Equal(t, int32(test.expectedAttempts), atomic.LoadInt32(&attempts), name)
+		letters, err = webhooks.DeadLetters("", "another-subscription")
+		require.NoError(t, err, name)
+		assert.Empty(t, letters, name)
+		letters, err = webhooks.DeadLetters("alerts", "")
+		require.NoError(t, err, name)
+		assert.Empty(t, letters, "%s: the dead letters of a subscription are only returned to its owner", name)
+		server.Close()
+	}
+}
+
+func TestWebhooksKeepTheirStateAcrossRestarts(t *testing.T) {
+	received := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "webhooks.db")
+	org := testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+
+	store, err := NewBoltWebhookStore(path)
+	require.NoError(t, err)
+	before, err := NewWebhooks(http.DefaultClient, store, WebhookConfig{MaxAttempts: 1}, logger.NewUPPInfoLogger("tests"), prometheus.NewRegistry())
+	require.NoError(t, err)
+	sub, err := before.Subscribe(Subscription{CallbackURL: server.URL, UUIDs: []string{"7c5218a0-3755-463e-abbc-1a1632cfd1da"}})
+	require.NoError(t, err)
+	before.Observe(org, "tid_first")
+	require.NoError(t, store.Close())
+
+	webhooks, err := NewWebhooks(http.DefaultClient, newTestBoltWebhookStore(t, path), WebhookConfig{MaxAttempts: 1},
+		logger.NewUPPInfoLogger("tests"), prometheus.NewRegistry())
+	require.NoError(t, err)
+	h := NewHandler(&mockHTTPClient{statusCode: 404}, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"), WithWebhooks(webhooks))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go webhooks.Run(ctx, &h)
+
+	restored, ok := webhooks.Subscription("", sub.ID)
+	require.True(t, ok, "the subscription should be loaded again")
+	assert.Equal(t, sub.CallbackURL, restored.CallbackURL)
+	org.PrefLabel = "Nintendo"
+	webhooks.Observe(org, "tid_changed")
+
+	select {
+	case body := <-received:
+		var payload WebhookPayload
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, []FieldChange{{Field: "prefLabel", Previous: "Nintendo Co Ltd", Current: "Nintendo"}}, payload.Changes,
+			"the change should be detected against the version seen before the restart")
+	case <-time.After(time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+func TestWebhooksAreRedactedForTheSubscriber(t *testing.T) {
+	bodies := make(chan []byte, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer server.Close()
+	auth := NewAuthenticator(AuthConfig{
+		APIKeys: []APIKey{
+			{Name: "company-pages", Key: "k1", Scopes: []string{"webhooks"}},
+			{Name: "compliance", Key: "k2", Scopes: []string{"organisations:lei", "webhooks"}},
+		},
+		FieldScopes: map[string][]string{"organisations:lei": {"leiCode"}},
+	}, logger.NewUPPInfoLogger("tests"), prometheus.NewRegistry())
+	webhooks, err := NewWebhooks(http.DefaultClient, NewMemoryWebhookStore(), WebhookConfig{MaxAttempts: 1, CallbackHosts: []string{"127.0.0.1"}}, logger.NewUPPInfoLogger("tests"), prometheus.NewRegistry())
+	require.NoError(t, err)
+	h := NewHandler(&mockHTTPClient{statusCode: 404}, "localhost:8080/concepts", logger.NewUPPInfoLogger("tests"), WithWebhooks(webhooks), WithAuthenticator(auth))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go webhooks.Run(ctx, &h)
+	router := mux.NewRouter()
+	h.RegisterWebhookHandlers(router, "webhooks")
+	router.Use(auth.Middleware)
+
+	subscribe := func(apiKey string) Subscription {
+		req := httptest.NewRequest("POST", "/webhooks/subscriptions", strings.NewReader(`{"callbackURL": "`+server.URL+`", "uuids": ["7c5218a0-3755-463e-abbc-1a1632cfd1da"], "scopes": ["organisations:lei"]}`))
+		req.Header.Set(apiKeyHeader, apiKey)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var sub Subscription
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sub))
+		return sub
+	}
+	pages := subscribe("k1")
+	assert.Equal(t, []string{"webhooks"}, pages.Scopes, "subscribers cannot ask for scopes they do not have")
+	compliance := subscribe("k2")
+	assert.Equal(t, []string{"organisations:lei", "webhooks"}, compliance.Scopes)
+
+	org := testCacheOrganisation("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+	org.LegalEntityIdentifier = "353800FEEXU6I9M0ZF27"
+	webhooks.Observe(org, "tid_first")
+	org.LegalEntityIdentifier = "549300X7F3KS3JZNB234"
+	webhooks.Observe(org, "tid_lei_changed")
+
+	var payload WebhookPayload
+	select {
+	case body := <-bodies:
+		require.NoError(t, json.Unmarshal(body, &payload))
+	case <-time.After(time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	assert.Equal(t, compliance.ID, payload.SubscriptionID, "only the subscriber entitled to the changed field should be told")
+	assert.Equal(t, "549300X7F3KS3JZNB234", payload.Organisation.LegalEntityIdentifier)
+
+	org.PrefLabel = "Nintendo"
+	org.LegalEntityIdentifier = "353800FEEXU6I9M0ZF27"
+	webhooks.Observe(org, "tid_changed")
+	for i := 0; i < 2; i++ {
+		select {
+		case body := <-bodies:
+			var payload WebhookPayload
+			require.NoError(t, json.Unmarshal(body, &payload))
+			if payload.SubscriptionID == pages.ID {
+				assert.NotContains(t, string(body), "leiCode")
+				assert.NotContains(t, string(body), "353800FEEXU6I9M0ZF27")
+				assert.Equal(t, []FieldChange{{Field: "prefLabel", Previous: "Nintendo Co Ltd", Current: "Nintendo"}}, payload.Changes)
+			} else {
+				assert.Len(t, payload.Changes, 2)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("webhook was not delivered")
+		}
+	}
+}
+
+// newTestSubscriptionRouter serves the webhook API of h to the callers auth authenticates with the webhooks scope
+func newTestSubscriptionRouter(h *OrganisationsHandler, auth *Authenticator) *mux.Router {
+	router := mux.NewRouter()
+	h.RegisterWebhookHandlers(router, "webhooks")
+	router.Use(auth.Middleware)
+	return router
+}
+
+func TestSubscriptionHandlers(t *testing.T) {
+	_, h := newTestWebhooks(t, 3)
+	auth := NewAuthenticator(AuthConfig{
+		APIKeys: []APIKey{
+			{Name: "company-pages", Key: "s3cr3t", Scopes: []string{"webhooks"}},
+			{Name: "alerts", Key: "4l3rts", Scopes: []string{"webhooks"}},
+			{Name: "search", Key: "0th3r"},
+		},
+	}, logger.NewUPPInfoLogger("tests"), prometheus.NewRegistry())
+	router := newTestSubscriptionRouter(h, auth)
+
+	requestAs := func(apiKey string, method string, url string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if apiKey != "" {
+			req.Header.Set(apiKeyHeader, apiKey)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	request := func(method string, url string, body string) *httptest.ResponseRecorder {
+		return requestAs("s3cr3t", method, url, body)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/webhooks/subscriptions", `{"callbackURL": "ftp://example.com", "uuids": ["7c5218a0-3755-463e-abbc-1a1632cfd1da"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/webhooks/subscriptions", `{"callbackURL": "https://example.com/hook", "uuids": ["nintendo"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/webhooks/subscriptions", `{"callbackURL": "https://example.com/hook", "uuids": []}`).Code)
+	for _, callback := range []string{"http://localhost:8080/__health", "http://127.0.0.1/hook", "http://[::1]/hook", "http://169.254.169.254/latest/meta-data", "https://internal.example.com/hook", "https://unknown.example.com/hook"} {
+		rec := request("POST", "/webhooks/subscriptions", `{"callbackURL": "`+callback+`", "uuids": ["7c5218a0-3755-463e-abbc-1a1632cfd1da"]}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "%s is not a callback outside of the network", callback)
+		assert.Contains(t, rec.Body.String(), `"code":"invalid-subscription"`, callback)
+	}
+
+	rec := request("POST", "/webhooks/subscriptions", `{"callbackURL": "https://example.com/hook", "uuids": ["7C5218A0-3755-463E-ABBC-1A1632CFD1DA", "7c5218a0-3755-463e-abbc-1a1632cfd1da"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created Subscription
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.NotEmpty(t, created.Secret)
+	assert.Equal(t, []string{"7c5218a0-3755-463e-abbc-1a1632cfd1da"}, created.UUIDs)
+	assert.Equal(t, "/webhooks/subscriptions/"+created.ID, rec.Header().Get("Location"))
+
+	rec = request("GET", "/webhooks/subscriptions", "")
+	var subs []Subscription
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&subs))
+	require.Len(t, subs, 1)
+	assert.Empty(t, subs[0].Secret, "secrets are only returned when the subscription is created")
+
+	assert.Equal(t, "company-pages", created.Owner)
+	assert.Equal(t, http.StatusOK, request("GET", "/webhooks/subscriptions/"+created.ID, "").Code)
+
+	assert.JSONEq(t, `[]`, requestAs("4l3rts", "GET", "/webhooks/subscriptions", "").Body.String(), "subscribers only see their own subscriptions")
+	assert.Equal(t, http.StatusNotFound, requestAs("4l3rts", "GET", "/webhooks/subscriptions/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, requestAs("4l3rts", "DELETE", "/webhooks/subscriptions/"+created.ID, "").Code, "subscribers cannot delete the subscriptions of others")
+	assert.JSONEq(t, `[]`, requestAs("4l3rts", "GET", "/webhooks/dead-letters?subscription="+created.ID, "").Body.String())
+	assert.Equal(t, http.StatusOK, request("GET", "/webhooks/dead-letters", "").Code)
+	assert.Equal(t, http.StatusNoContent, request("DELETE", "/webhooks/subscriptions/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, request("GET", "/webhooks/subscriptions/"+created.ID, "").Code)
+
+	assert.Equal(t, http.StatusUnauthorized, requestAs("", "GET", "/webhooks/subscriptions", "").Code)
+	forbidden := requestAs("0th3r", "GET", "/webhooks/subscriptions", "")
+	assert.Equal(t, http.StatusForbidden, forbidden.Code, "callers need the webhooks scope")
+	assert.Contains(t, forbidden.Body.String(), `"code":"forbidden"`)
+}
+
+func TestSubscriptionHandlersAcceptJWTs(t *testing.T) {
+	_, h := newTestWebhooks(t, 3)
+	signer := newTestSigner(t)
+	keys, err := ParseJSONWebKeySet(signer.jwks)
+	require.NoError(t, err)
+	router := newTestSubscriptionRouter(h, NewAuthenticator(AuthConfig{Keys: keys}, logger.NewUPPInfoLogger("tests"), prometheus.NewRegistry()))
+
+	subscribe := func(scope string) *httptest.ResponseRecorder {
+		token := signer.sign(t, map[string]string{"alg": "RS256", "kid": "rsa"}, map[string]interface{}{
+			"sub": "company-pages", "scope": scope, "exp": time.Now().Add(time.Hour).Unix()})
+		req := httptest.NewRequest("POST", "/webhooks/subscriptions", strings.NewReader(`{"callbackURL": "https://example.com/hook", "uuids": ["7c5218a0-3755-463e-abbc-1a1632cfd1da"]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := subscribe("webhooks")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created Subscription
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.Equal(t, []string{"7c5218a0-3755-463e-abbc-1a1632cfd1da"}, created.UUIDs)
+	assert.Equal(t, http.StatusForbidden, subscribe("organisations:lei").Code)
+}
@@ -0,0 +1,252 @@
+package organisations
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	subscriptionsBucket = []byte("subscriptions")
+	lastSeenBucket      = []byte("last-seen")
+	deadLettersBucket   = []byte("dead-letters")
+)
+
+// WebhookStore keeps the state of the webhooks: the subscriptions, the version of each subscribed organisation
+// changes are detected against, and the webhooks that could not be delivered
+type WebhookStore interface {
+	// Subscriptions returns every subscription, with its secret
+	Subscriptions() ([]Subscription, error)
+	SaveSubscription(sub Subscription) error
+	DeleteSubscription(id string) error
+	// LastSeen returns the fields of the version of the organisation seen last, if any was
+	LastSeen(uuid string) (fields map[string]interface{}, seen bool, err error)
+	SetLastSeen(uuid string, fields map[string]interface{}) error
+	DeleteLastSeen(uuid string) error
+	// AddDeadLetter saves the dead letter, keeping only the latest max ones
+	AddDeadLetter(letter DeadLetter, max int) error
+	// DeadLetters returns the saved dead letters, oldest first
+	DeadLetters() ([]DeadLetter, error)
+	Close() error
+}
+
+// MemoryWebhookStore is a WebhookStore kept in memory, losing the subscriptions when the service restarts
+type MemoryWebhookStore struct {
+	mu            sync.Mutex
+	subscriptions map[string]Subscription
+	lastSeen      map[string]map[string]interface{}
+	deadLetters   []DeadLetter
+}
+
+// NewMemoryWebhookStore creates an empty store
+func NewMemoryWebhookStore() *MemoryWebhookStore {
+	return &MemoryWebhookStore{
+		subscriptions: map[string]Subscription{},
+		lastSeen:      map[string]map[string]interface{}{},
+	}
+}
+
+func (s *MemoryWebhookStore) Subscriptions() ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subs := make([]Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+func (s *MemoryWebhookStore) SaveSubscription(sub Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions[sub.ID] = sub
+	return nil
+}
+
+func (s *MemoryWebhookStore) DeleteSubscription(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, id)
+	return nil
+}
+
+func (s *MemoryWebhookStore) LastSeen(uuid string) (map[string]interface{}, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fields, ok := s.lastSeen[uuid]
+	return fields, ok, nil
+}
+
+func (s *MemoryWebhookStore) SetLastSeen(uuid string, fields map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSeen[uuid] = fields
+	return nil
+}
+
+func (s *MemoryWebhookStore) DeleteLastSeen(uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.lastSeen, uuid)
+	return nil
+}
+
+func (s *MemoryWebhookStore) AddDeadLetter(letter DeadLetter, max int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters = append(s.deadLetters, letter)
+	if len(s.deadLetters) > max {
+		s.deadLetters = s.deadLetters[len(s.deadLetters)-max:]
+	}
+	return nil
+}
+
+func (s *MemoryWebhookStore) DeadLetters() ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]DeadLetter{}, s.deadLetters...), nil
+}
+
+func (s *MemoryWebhookStore) Close() error {
+	return nil
+}
+
+// BoltWebhookStore is a WebhookStore kept in an embedded bbolt database, so that subscriptions survive restarts.
+// A bbolt database is only opened by one process at a time, so the state is not shared between instances.
+type BoltWebhookStore struct {
+	db *bolt.DB
+}
+
+// NewBoltWebhookStore opens, or creates, the webhook database at path
+func NewBoltWebhookStore(path string) (*BoltWebhookStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening webhook database %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{subscriptionsBucket, lastSeenBucket, deadLettersBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating webhook buckets: %w", err)
+	}
+	return &BoltWebhookStore{db: db}, nil
+}
+
+func (s *BoltWebhookStore) Subscriptions() ([]Subscription, error) {
+	subs := []Subscription{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(subscriptionsBucket).ForEach(func(_, v []byte) error {
+			sub := Subscription{}
+			if err := json.Unmarshal(v, &sub); err != nil {
+				return err
+			}
+			subs = append(subs, sub)
+			return nil
+		})
+	})
+	return subs, err
+}
+
+func (s *BoltWebhookStore) SaveSubscription(sub Subscription) error {
+	return s.put(subscriptionsBucket, sub.ID, sub)
+}
+
+func (s *BoltWebhookStore) DeleteSubscription(id string) error {
+	return s.delete(subscriptionsBucket, id)
+}
+
+func (s *BoltWebhookStore) LastSeen(uuid string) (map[string]interface{}, bool, error) {
+	var fields map[string]interface{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(lastSeenBucket).Get([]byte(uuid))
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &fields)
+	})
+	return fields, fields != nil, err
+}
+
+func (s *BoltWebhookStore) SetLastSeen(uuid string, fields map[string]interface{}) error {
+	return s.put(lastSeenBucket, uuid, fields)
+}
+
+func (s *BoltWebhookStore) DeleteLastSeen(uuid string) error {
+	return s.delete(lastSeenBucket, uuid)
+}
+
+func (s *BoltWebhookStore) AddDeadLetter(letter DeadLetter, max int) error {
+	v, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(deadLettersBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		if err := b.Put(key, v); err != nil {
+			return err
+		}
+		// Keys are sequential, so the oldest dead letters are the ones at least max before the new one
+		var expired [][]byte
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k)+uint64(max) <= seq; k, _ = c.Next() {
+			expired = append(expired, append([]byte{}, k...))
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltWebhookStore) DeadLetters() ([]DeadLetter, error) {
+	letters := []DeadLetter{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deadLettersBucket).ForEach(func(_, v []byte) error {
+			letter := DeadLetter{}
+			if err := json.Unmarshal(v, &letter); err != nil {
+				return err
+			}
+			letters = append(letters, letter)
+			return nil
+		})
+	})
+	return letters, err
+}
+
+func (s *BoltWebhookStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltWebhookStore) put(bucket []byte, key string, value interface{}) error {
+	v, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), v)
+	})
+}
+
+func (s *BoltWebhookStore) delete(bucket []byte, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(key))
+	})
+}
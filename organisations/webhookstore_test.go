@@ -0,0 +1,56 @@
+package organisations
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBoltWebhookStore(t *testing.T, path string) *BoltWebhookStore {
+	store, err := NewBoltWebhookStore(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestWebhookStores(t *testing.T) {
+	for name, store := range map[string]WebhookStore{
+		"memory": NewMemoryWebhookStore(),
+		"bolt":   newTestBoltWebhookStore(t, filepath.Join(t.TempDir(), "webhooks.db")),
+	} {
+		sub := Subscription{ID: "a1", CallbackURL: "https://example.com/hooks", UUIDs: []string{"7c5218a0-3755-463e-abbc-1a1632cfd1da"},
+			Secret: "s3cret", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+		require.NoError(t, store.SaveSubscription(sub), name)
+		subs, err := store.Subscriptions()
+		require.NoError(t, err, name)
+		assert.Equal(t, []Subscription{sub}, subs, name)
+		require.NoError(t, store.DeleteSubscription(sub.ID), name)
+		subs, err = store.Subscriptions()
+		require.NoError(t, err, name)
+		assert.Empty(t, subs, name)
+
+		_, seen, err := store.LastSeen("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+		require.NoError(t, err, name)
+		assert.False(t, seen, name)
+		require.NoError(t, store.SetLastSeen("7c5218a0-3755-463e-abbc-1a1632cfd1da", map[string]interface{}{"prefLabel": "Nintendo"}), name)
+		fields, seen, err := store.LastSeen("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+		require.NoError(t, err, name)
+		assert.True(t, seen, name)
+		assert.Equal(t, map[string]interface{}{"prefLabel": "Nintendo"}, fields, name)
+		require.NoError(t, store.DeleteLastSeen("7c5218a0-3755-463e-abbc-1a1632cfd1da"), name)
+		_, seen, err = store.LastSeen("7c5218a0-3755-463e-abbc-1a1632cfd1da")
+		require.NoError(t, err, name)
+		assert.False(t, seen, name)
+
+		for _, attempts := range []int{1, 2, 3, 4} {
+			require.NoError(t, store.AddDeadLetter(DeadLetter{Attempts: attempts}, 3), name)
+		}
+		letters, err := store.DeadLetters()
+		require.NoError(t, err, name)
+		require.Len(t, letters, 3, "%s should only keep the latest dead letters", name)
+		assert.Equal(t, []int{2, 3, 4}, []int{letters[0].Attempts, letters[1].Attempts, letters[2].Attempts}, name)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/Financial-Times/public-organisations-api/v3/organisations"
+	"github.com/Financial-Times/service-status-go/gtg"
+)
+
+// serverConfig holds the timeouts of the HTTP server and how long it takes to shut down
+type serverConfig struct {
+	port              string
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	// preStopDelay is how long /__gtg fails before the server stops accepting connections,
+	// giving load balancers time to take the instance out of rotation
+	preStopDelay time.Duration
+	// shutdownTimeout bounds how long in-flight requests may take to drain
+	shutdownTimeout time.Duration
+	// healthInterval is how often the health checks run in the background
+	healthInterval time.Duration
+	healthTimeout  time.Duration
+	// healthDegradedLatency is how long a passing health check may take before it is reported as degraded
+	healthDegradedLatency time.Duration
+	// canaryUUID is the organisation read by the canary health check, which is disabled when it is empty
+	canaryUUID string
+	// adminToken is the bearer token of the /__admin endpoints, which are disabled when it is empty
+	adminToken string
+	// conceptEventsBrokers are the Kafka brokers of the concept change notifications, which are not consumed when empty
+	conceptEventsBrokers []string
+	conceptEventsTopic   string
+	conceptEventsRefresh bool
+	// webhookScope is the scope callers of the webhook subscription API need, which is disabled when it is empty
+	webhookScope string
+	// webhookDB is the path of the store of the webhook state, which is kept in memory when it is empty
+	webhookDB      string
+	webhookTimeout time.Duration
+	webhooks       organisations.WebhookConfig
+}
+
+// shutdownGate fails the good to go check once the service has started shutting down
+type shutdownGate struct {
+	draining atomic.Bool
+}
+
+func (g *shutdownGate) drain() {
+	g.draining.Store(true)
+}
+
+// gtg wraps check so that it fails while the service is draining
+func (g *shutdownGate) gtg(check func() gtg.Status) func() gtg.Status {
+	return func() gtg.Status {
+		if g.draining.Load() {
+			return gtg.Status{GoodToGo: false, Message: "Service is shutting down"}
+		}
+		return check()
+	}
+}
+
+// serve runs the server until SIGTERM or an interrupt, then fails /__gtg for the pre-stop delay
+// and drains in-flight requests before returning
+func serve(cfg serverConfig, handler http.Handler, gate *shutdownGate, ftLogger *logger.UPPLogger) {
+	listener, err := net.Listen("tcp", ":"+cfg.port)
+	if err != nil {
+		ftLogger.Fatalf("Unable to start server: %v", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+
+	serveUntilSignalled(cfg, listener, handler, gate, signals, ftLogger)
+}
+
+// serveUntilSignalled serves the connections of listener until a signal is received, then shuts the server down
+func serveUntilSignalled(cfg serverConfig, listener net.Listener, handler http.Handler, gate *shutdownGate, signals <-chan os.Signal, ftLogger *logger.UPPLogger) {
+	server := &http.Server{
+		Handler:           handler,
+		ReadTimeout:       cfg.readTimeout,
+		ReadHeaderTimeout: cfg.readHeaderTimeout,
+		WriteTimeout:      cfg.writeTimeout,
+		IdleTimeout:       cfg.idleTimeout,
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serverErrors:
+		ftLogger.Fatalf("Unable to start server: %v", err)
+	case sig := <-signals:
+		ftLogger.Infof("[Shutdown] Received %v, failing good to go for %v before draining requests", sig, cfg.preStopDelay)
+	}
+
+	gate.drain()
+	time.Sleep(cfg.preStopDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		ftLogger.WithError(err).Error("[Shutdown] Requests did not drain before the shutdown timeout")
+		server.Close()
+	}
+	if err := <-serverErrors; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		ftLogger.WithError(err).Error("[Shutdown] Server stopped with an error")
+	}
+	ftLogger.Info("[Shutdown] public-organisations-api has stopped")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/Financial-Times/service-status-go/gtg"
+	status "github.com/Financial-Times/service-status-go/httphandlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerDrainsRequestsWhenShuttingDown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	baseURL := "http://" + listener.Addr().String()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	gate := &shutdownGate{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+	mux.HandleFunc(status.GTGPath, status.NewGoodToGoHandler(gate.gtg(func() gtg.Status {
+		return gtg.Status{GoodToGo: true}
+	})))
+
+	cfg := serverConfig{preStopDelay: 500 * time.Millisecond, shutdownTimeout: 5 * time.Second}
+	signals := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	go func() {
+		serveUntilSignalled(cfg, listener, mux, gate, signals, logger.NewUPPInfoLogger("tests"))
+		close(stopped)
+	}()
+
+	// Each probe opens a connection of its own, so that it shows whether new connections are accepted
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: time.Second}
+	resp, err := client.Get(baseURL + status.GTGPath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/slow")
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inFlight <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+	<-started
+
+	signals <- syscall.SIGTERM
+	assert.Eventually(t, func() bool {
+		resp, err := client.Get(baseURL + status.GTGPath)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, cfg.preStopDelay, 10*time.Millisecond, "good to go fails while the load balancers take the instance out of rotation")
+
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			return true
+		}
+		conn.Close()
+		return false
+	}, 2*time.Second, 10*time.Millisecond, "new connections are refused once the server shuts down")
+
+	select {
+	case <-stopped:
+		t.Fatal("the server stopped before the request in flight completed")
+	default:
+	}
+	close(release)
+
+	select {
+	case r := <-inFlight:
+		require.NoError(t, r.err)
+		assert.Equal(t, http.StatusOK, r.status, "the request in flight when shutdown began completes")
+		assert.Equal(t, "done", r.body)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the request in flight did not complete")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the server did not stop once the requests drained")
+	}
+
+	_, err = net.Dial("tcp", listener.Addr().String())
+	assert.Error(t, err, "no connections are accepted after the server stopped")
+}